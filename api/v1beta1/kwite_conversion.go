@@ -0,0 +1,207 @@
+/*
+kwite_conversion.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tdhite/kwite-operator/api/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+const (
+	// Annotation holding the v1beta2 spec fields the v1beta1 representation
+	// cannot carry, so round trips are lossless. The status is not kept, as
+	// it is only ever written through the status subresource.
+	ConversionDataAnnotation = "web.kwite.site/conversion-data"
+)
+
+var _ conversion.Convertible = &Kwite{}
+
+// hubData is the portion of the hub persisted in the conversion annotation.
+type hubData struct {
+	Spec v1beta2.KwiteSpec `json:"spec,omitempty"`
+}
+
+// ConvertTo converts this Kwite to the Hub version (v1beta2).
+func (src *Kwite) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta2.Kwite)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+
+	// restore anything v1beta1 could not represent, then lay the v1beta1
+	// fields over it since those are what the client actually sent.
+	if s, ok := dst.Annotations[ConversionDataAnnotation]; ok {
+		var data hubData
+		if err := json.Unmarshal([]byte(s), &data); err != nil {
+			return err
+		}
+		dst.Spec = data.Spec
+		delete(dst.Annotations, ConversionDataAnnotation)
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+	}
+
+	if err := src.Spec.convertTo(&dst.Spec); err != nil {
+		return err
+	}
+	src.Status.convertTo(&dst.Status)
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta2) to this version.
+func (dst *Kwite) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta2.Kwite)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	delete(dst.Annotations, ConversionDataAnnotation)
+
+	dst.Spec.convertFrom(&src.Spec)
+	dst.Status.convertFrom(&src.Status)
+
+	// stash only what the v1beta1 fields do not carry, if anything
+	rest := residualSpec(&src.Spec)
+	if apiequality.Semantic.DeepEqual(rest, &v1beta2.KwiteSpec{}) {
+		return nil
+	}
+
+	b, err := json.Marshal(hubData{Spec: *rest})
+	if err != nil {
+		return err
+	}
+	if dst.Annotations == nil {
+		dst.Annotations = make(map[string]string)
+	}
+	dst.Annotations[ConversionDataAnnotation] = string(b)
+
+	return nil
+}
+
+// Return a copy of the v1beta2 spec without the fields the v1beta1 spec
+// carries, those convertFrom sets.
+func residualSpec(src *v1beta2.KwiteSpec) *v1beta2.KwiteSpec {
+	rest := src.DeepCopy()
+
+	rest.Image = ""
+	rest.ImagePullSecrets = nil
+	rest.SecurityContext = nil
+
+	rest.Exposure.Url = ""
+	rest.Exposure.Port = 0
+	rest.Exposure.Public = nil
+
+	rest.Scaling.MinReplicas = 0
+	rest.Scaling.MaxReplicas = 0
+	rest.Scaling.TargetCPU = 0
+
+	delete(rest.Resources.Requests, corev1.ResourceCPU)
+	delete(rest.Resources.Requests, corev1.ResourceMemory)
+	if len(rest.Resources.Requests) == 0 {
+		rest.Resources.Requests = nil
+	}
+
+	rest.Probes.Ready.Inline = ""
+	rest.Probes.Alive.Inline = ""
+
+	rest.Template.Inline = ""
+
+	return rest
+}
+
+// Convert the flat v1beta1 spec into the grouped v1beta2 spec.
+func (src *KwiteSpec) convertTo(dst *v1beta2.KwiteSpec) error {
+	dst.Image = src.Image
+	dst.ImagePullSecrets = src.ImagePullSecrets
+	dst.SecurityContext = src.SecurityContext
+
+	dst.Exposure.Url = src.Url
+	dst.Exposure.Port = int32(src.Port)
+	dst.Exposure.Public = src.Public
+
+	dst.Scaling.MinReplicas = int32(src.MinReplicas)
+	dst.Scaling.MaxReplicas = int32(src.MaxReplicas)
+	dst.Scaling.TargetCPU = int32(src.TargetCpu)
+
+	// only the requests are representable in v1beta1
+	for name, value := range map[corev1.ResourceName]string{corev1.ResourceCPU: src.CPU, corev1.ResourceMemory: src.Memory} {
+		if value == "" {
+			delete(dst.Resources.Requests, name)
+			continue
+		}
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return fmt.Errorf("spec.%s: %v", strings.ToLower(string(name)), err)
+		}
+		if dst.Resources.Requests == nil {
+			dst.Resources.Requests = make(corev1.ResourceList)
+		}
+		dst.Resources.Requests[name] = q
+	}
+
+	dst.Probes.Ready.Inline = src.Ready
+	dst.Probes.Alive.Inline = src.Alive
+
+	dst.Template.Inline = src.Template
+
+	return nil
+}
+
+// Convert the grouped v1beta2 spec into the flat v1beta1 spec.
+func (dst *KwiteSpec) convertFrom(src *v1beta2.KwiteSpec) {
+	dst.Image = src.Image
+	dst.ImagePullSecrets = src.ImagePullSecrets
+	dst.SecurityContext = src.SecurityContext
+
+	dst.Url = src.Exposure.Url
+	dst.Port = int(src.Exposure.Port)
+	dst.Public = src.Exposure.Public
+
+	dst.MinReplicas = int(src.Scaling.MinReplicas)
+	dst.MaxReplicas = int(src.Scaling.MaxReplicas)
+	dst.TargetCpu = int(src.Scaling.TargetCPU)
+
+	dst.CPU = quantityString(src.Resources.Requests, corev1.ResourceCPU)
+	dst.Memory = quantityString(src.Resources.Requests, corev1.ResourceMemory)
+
+	dst.Ready = src.Probes.Ready.Inline
+	dst.Alive = src.Probes.Alive.Inline
+
+	dst.Template = src.Template.Inline
+}
+
+// Return the quantity of the named resource as a string, "" when unset. The
+// string is the canonical form of the quantity, as the hub stores it, so a
+// v1beta1 "0.5" reads back as "500m".
+func quantityString(list corev1.ResourceList, name corev1.ResourceName) string {
+	if q, ok := list[name]; ok {
+		return q.String()
+	}
+	return ""
+}
+
+// Convert the v1beta1 status into the v1beta2 status.
+func (src *KwiteStatus) convertTo(dst *v1beta2.KwiteStatus) {
+	dst.Address = src.Address
+	dst.ReadyReplicas = int32(src.ReadyReplicas)
+	dst.DesiredReplicas = int32(src.DesiredReplicas)
+	dst.Ready = src.Ready
+}
+
+// Convert the v1beta2 status into the v1beta1 status.
+func (dst *KwiteStatus) convertFrom(src *v1beta2.KwiteStatus) {
+	dst.Address = src.Address
+	dst.ReadyReplicas = int(src.ReadyReplicas)
+	dst.DesiredReplicas = int(src.DesiredReplicas)
+	dst.Ready = src.Ready
+}
@@ -0,0 +1,234 @@
+/*
+kwite_conversion_test.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package v1beta1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tdhite/kwite-operator/api/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHubRoundTrip(t *testing.T) {
+	public := true
+	autoscaling := false
+	replicas := int32(3)
+
+	tests := []struct {
+		name     string
+		spec     v1beta2.KwiteSpec
+		stashed  bool
+		excluded []string
+	}{
+		{
+			name: "v1beta1 fields only",
+			spec: v1beta2.KwiteSpec{
+				Image:            "kwite:latest",
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "pull"}},
+				Exposure:         v1beta2.KwiteExposure{Url: "/", Port: 8080, Public: &public},
+				Scaling:          v1beta2.KwiteScaling{MinReplicas: 1, MaxReplicas: 4, TargetCPU: 80},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("200m"),
+						corev1.ResourceMemory: resource.MustParse("64Mi"),
+					},
+				},
+				Probes: v1beta2.KwiteProbes{
+					Ready: v1beta2.KwiteProbe{KwiteTemplate: v1beta2.KwiteTemplate{Inline: "ready"}},
+					Alive: v1beta2.KwiteProbe{KwiteTemplate: v1beta2.KwiteTemplate{Inline: "alive"}},
+				},
+				Template: v1beta2.KwiteTemplate{Inline: "hello"},
+			},
+		},
+		{
+			name: "v1beta2 fields",
+			spec: v1beta2.KwiteSpec{
+				Image:    "kwite:latest",
+				Exposure: v1beta2.KwiteExposure{Url: "/", Port: 8080},
+				Scaling:  v1beta2.KwiteScaling{Autoscaling: &autoscaling, Replicas: &replicas, MinReplicas: 1},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:              resource.MustParse("0.5"),
+						corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+					},
+					Limits: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("1"),
+					},
+				},
+				Probes: v1beta2.KwiteProbes{
+					Ready: v1beta2.KwiteProbe{KwiteTemplate: v1beta2.KwiteTemplate{
+						From: &v1beta2.TemplateSource{SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "probes"},
+							Key:                  "ready",
+						}},
+					}},
+				},
+				Template: v1beta2.KwiteTemplate{Inline: "main template"},
+				Routes: []v1beta2.KwiteRoute{
+					{Path: "/other", Template: v1beta2.KwiteTemplate{Inline: "other"}},
+				},
+				Files: map[string]v1beta2.KwiteFile{
+					"style.css": {Text: "body {}"},
+					"logo.png":  {Binary: []byte{0x89, 'P', 'N', 'G'}},
+				},
+				Env: []corev1.EnvVar{{Name: "A", Value: "b"}},
+			},
+			stashed:  true,
+			excluded: []string{"main template", "kwite:latest", "status"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := &v1beta2.Kwite{
+				ObjectMeta: metav1.ObjectMeta{Name: "kwite", Namespace: "default"},
+				Spec:       tt.spec,
+				Status: v1beta2.KwiteStatus{
+					Address: "kwite.default.svc.cluster.local:8080",
+					Ready:   true,
+				},
+			}
+
+			old := &Kwite{}
+			if err := old.ConvertFrom(hub); err != nil {
+				t.Fatalf("ConvertFrom: %v", err)
+			}
+			data, stashed := old.Annotations[ConversionDataAnnotation]
+			if stashed != tt.stashed {
+				t.Fatalf("conversion data stashed = %v, want %v", stashed, tt.stashed)
+			}
+			for _, s := range tt.excluded {
+				if strings.Contains(data, s) {
+					t.Errorf("conversion data holds %q: %s", s, data)
+				}
+			}
+
+			back := &v1beta2.Kwite{}
+			if err := old.ConvertTo(back); err != nil {
+				t.Fatalf("ConvertTo: %v", err)
+			}
+			if !apiequality.Semantic.DeepEqual(back.Spec, hub.Spec) {
+				t.Errorf("spec changed in round trip:\n got %+v\nwant %+v", back.Spec, hub.Spec)
+			}
+			if back.Status.Address != hub.Status.Address || back.Status.Ready != hub.Status.Ready {
+				t.Errorf("status changed in round trip: got %+v, want %+v", back.Status, hub.Status)
+			}
+			if _, ok := back.Annotations[ConversionDataAnnotation]; ok {
+				t.Errorf("conversion data left on the hub")
+			}
+		})
+	}
+}
+
+// The conversion data holds a large file once, without the fields v1beta1
+// carries, so it stays near the size of the file.
+func TestHubConversionDataSize(t *testing.T) {
+	file := strings.Repeat("x", 100*1024)
+	hub := &v1beta2.Kwite{
+		ObjectMeta: metav1.ObjectMeta{Name: "kwite", Namespace: "default"},
+		Spec: v1beta2.KwiteSpec{
+			Template: v1beta2.KwiteTemplate{Inline: strings.Repeat("t", 100*1024)},
+			Files:    map[string]v1beta2.KwiteFile{"big.txt": {Text: file}},
+		},
+		Status: v1beta2.KwiteStatus{
+			Conditions: []v1beta2.KwiteCondition{{Type: v1beta2.KwiteAvailable, Message: strings.Repeat("m", 1024)}},
+		},
+	}
+
+	old := &Kwite{}
+	if err := old.ConvertFrom(hub); err != nil {
+		t.Fatalf("ConvertFrom: %v", err)
+	}
+	if size, max := len(old.Annotations[ConversionDataAnnotation]), len(file)+1024; size > max {
+		t.Errorf("conversion data is %d bytes, want no more than %d", size, max)
+	}
+}
+
+func TestV1beta1RoundTrip(t *testing.T) {
+	public := false
+
+	tests := []struct {
+		name string
+		spec KwiteSpec
+		want KwiteSpec
+	}{
+		{
+			name: "canonical quantities",
+			spec: KwiteSpec{
+				Url:              "/",
+				Public:           &public,
+				Image:            "kwite:latest",
+				Port:             8080,
+				MinReplicas:      1,
+				MaxReplicas:      3,
+				Memory:           "64Mi",
+				CPU:              "200m",
+				TargetCpu:        80,
+				ImagePullSecrets: []corev1.LocalObjectReference{},
+				Template:         "hello",
+				Ready:            "ready",
+				Alive:            "alive",
+			},
+		},
+		{
+			name: "unset requests",
+			spec: KwiteSpec{Url: "/kwite", Template: "hello"},
+		},
+		{
+			// the hub stores quantities in canonical form
+			name: "canonicalized quantities",
+			spec: KwiteSpec{Url: "/", CPU: "0.5", Memory: "0.5Gi", Template: "hello"},
+			want: KwiteSpec{Url: "/", CPU: "500m", Memory: "512Mi", Template: "hello"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if apiequality.Semantic.DeepEqual(want, KwiteSpec{}) {
+				want = tt.spec
+			}
+			old := &Kwite{
+				ObjectMeta: metav1.ObjectMeta{Name: "kwite", Namespace: "default"},
+				Spec:       tt.spec,
+				Status:     KwiteStatus{Address: "10.0.0.1:8080", ReadyReplicas: 1, DesiredReplicas: 1, Ready: true},
+			}
+
+			hub := &v1beta2.Kwite{}
+			if err := old.ConvertTo(hub); err != nil {
+				t.Fatalf("ConvertTo: %v", err)
+			}
+			back := &Kwite{}
+			if err := back.ConvertFrom(hub); err != nil {
+				t.Fatalf("ConvertFrom: %v", err)
+			}
+
+			if !apiequality.Semantic.DeepEqual(back.Spec, want) {
+				t.Errorf("spec changed in round trip:\n got %+v\nwant %+v", back.Spec, want)
+			}
+			if !apiequality.Semantic.DeepEqual(back.Status, old.Status) {
+				t.Errorf("status changed in round trip: got %+v, want %+v", back.Status, old.Status)
+			}
+			if _, ok := back.Annotations[ConversionDataAnnotation]; ok {
+				t.Errorf("conversion data stashed for a v1beta1 kwite")
+			}
+		})
+	}
+}
+
+func TestV1beta1InvalidQuantity(t *testing.T) {
+	old := &Kwite{Spec: KwiteSpec{CPU: "lots"}}
+	if err := old.ConvertTo(&v1beta2.Kwite{}); err == nil {
+		t.Errorf("ConvertTo accepted cpu %q", old.Spec.CPU)
+	}
+}
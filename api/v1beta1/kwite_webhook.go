@@ -9,16 +9,9 @@ SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
 package v1beta1
 
 import (
-	"text/template"
-
-	"github.com/tdhite/kwite/pkg/funcs"
-	corev1 "k8s.io/api/core/v1"
+	"github.com/tdhite/kwite-operator/api/v1beta2"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	validationutils "k8s.io/apimachinery/pkg/util/validation"
-	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -37,61 +30,19 @@ func (r *Kwite) SetupWebhookWithManager(mgr ctrl.Manager) error {
 
 var _ webhook.Defaulter = &Kwite{}
 
-// Default implements webhook.Defaulter so a webhook will be registered for the type
+// Default implements webhook.Defaulter so a webhook will be registered for the
+// type. Defaulting is done by the hub version so both versions agree.
 func (r *Kwite) Default() {
 	kwitelog.Info("default", "name", r.Name)
 
-	if r.Spec.Url == "" {
-		r.Spec.Url = "/"
-	}
-
-	if r.Spec.Image == "" {
-		r.Spec.Image = "kwite:latest"
-	}
-
-	if r.Spec.Port == 0 {
-		r.Spec.Port = 8080
-	}
-
-	if r.Spec.MaxReplicas <= 0 {
-		r.Spec.MaxReplicas = 1
+	hub := &v1beta2.Kwite{}
+	if err := r.ConvertTo(hub); err != nil {
+		kwitelog.Error(err, "Failed conversion to hub for defaulting", "name", r.Name)
+		return
 	}
-
-	if r.Spec.MinReplicas <= 0 {
-		r.Spec.MinReplicas = 0
-	}
-
-	if r.Spec.Public == nil {
-		r.Spec.Public = new(bool)
-	}
-
-	if r.Spec.Memory == "" {
-		r.Spec.Memory = "64Mi"
-	}
-
-	if r.Spec.CPU == "" {
-		r.Spec.CPU = "200m"
-	}
-
-	if r.Spec.TargetCpu == 0 {
-		r.Spec.TargetCpu = 80
-	}
-
-	if r.Spec.ImagePullSecrets == nil {
-		r.Spec.ImagePullSecrets = []corev1.LocalObjectReference{}
-	}
-
-	if r.Spec.SecurityContext == nil {
-		nonRoot := true
-		readOnly := true
-		allowEscalate := false
-		var user int64 = 65534
-		r.Spec.SecurityContext = &corev1.SecurityContext{
-			RunAsNonRoot:             &nonRoot,
-			ReadOnlyRootFilesystem:   &readOnly,
-			AllowPrivilegeEscalation: &allowEscalate,
-			RunAsUser:                &user,
-		}
+	hub.Default()
+	if err := r.ConvertFrom(hub); err != nil {
+		kwitelog.Error(err, "Failed conversion from hub for defaulting", "name", r.Name)
 	}
 }
 
@@ -103,14 +54,26 @@ var _ webhook.Validator = &Kwite{}
 func (r *Kwite) ValidateCreate() error {
 	kwitelog.Info("validate create", "name", r.Name)
 
-	return r.validateKwite()
+	hub, err := r.toHub()
+	if err != nil {
+		return err
+	}
+	return hub.ValidateCreate()
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *Kwite) ValidateUpdate(old runtime.Object) error {
 	kwitelog.Info("validate update", "name", r.Name)
 
-	return r.validateKwite()
+	hub, err := r.toHub()
+	if err != nil {
+		return err
+	}
+	oldHub, err := old.(*Kwite).toHub()
+	if err != nil {
+		return err
+	}
+	return hub.ValidateUpdate(oldHub)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -120,75 +83,11 @@ func (r *Kwite) ValidateDelete() error {
 	return nil
 }
 
-// Validate the kwite object
-func (r *Kwite) validateKwite() error {
-	var allErrs field.ErrorList
-	allErrs = r.validateKwiteName(allErrs)
-	allErrs = r.validateKwiteSpec(allErrs)
-
-	if len(allErrs) == 0 {
-		return nil
-	}
-
-	return apierrors.NewInvalid(
-		schema.GroupKind{Group: "web.kwite.site", Kind: ControllerName},
-		r.Name, allErrs)
-}
-
-// Validate that the Kwite name conforms to the rules on object fields
-func (r *Kwite) validateKwiteName(allErrs field.ErrorList) field.ErrorList {
-	if len(r.ObjectMeta.Name) > validationutils.DNS1035LabelMaxLength {
-		// The kwite name length is 63 character like all Kubernetes objects
-		// (which must fit in a DNS subdomain).
-		fe := field.Invalid(field.NewPath("metadata").Child("name"), r.Name, "must be no more than 63 characters")
-		allErrs = append(allErrs, fe)
-	}
-	return allErrs
-}
-
-// Validate the Kwite Spec object
-func (r *Kwite) validateKwiteSpec(allErrs field.ErrorList) field.ErrorList {
-	// The field helpers from the kubernetes API machinery help us return nicely
-	// structured validation errors.
-
-	fldPath := field.NewPath("spec")
-
-	if fe := r.validateQuantity(fldPath, "CPU", r.Spec.CPU); fe != nil {
-		allErrs = append(allErrs, fe)
-	}
-
-	if fe := r.validateQuantity(fldPath, "Memory", r.Spec.Memory); fe != nil {
-		allErrs = append(allErrs, fe)
-	}
-
-	if fe := r.validateTemplate(fldPath, "template", &r.Spec.Template); fe != nil {
-		allErrs = append(allErrs, fe)
-	}
-
-	if fe := r.validateTemplate(fldPath, "ready", &r.Spec.Ready); fe != nil {
-		allErrs = append(allErrs, fe)
-	}
-
-	if fe := r.validateTemplate(fldPath, "alive", &r.Spec.Alive); fe != nil {
-		allErrs = append(allErrs, fe)
-	}
-
-	return allErrs
-}
-
-// Validate that the quantity conforms to the rules on Kubernetes quantities.
-func (r *Kwite) validateQuantity(fldPath *field.Path, name, value interface{}) *field.Error {
-	if _, err := resource.ParseQuantity(value.(string)); err != nil {
-		return field.Invalid(fldPath, value, err.Error())
-	}
-	return nil
-}
-
-// Validate that the Template within the Spec parses successfully
-func (r *Kwite) validateTemplate(fldPath *field.Path, name string, t *string) *field.Error {
-	_, err := template.New(name).Funcs(funcs.TextTemplateFuncs()).Parse(string(*t))
-	if err != nil {
-		return field.Invalid(fldPath.Child(name), r.Name, err.Error())
+// Convert the kwite to the hub version, where validation is implemented
+func (r *Kwite) toHub() (*v1beta2.Kwite, error) {
+	hub := &v1beta2.Kwite{}
+	if err := r.ConvertTo(hub); err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
 	}
-	return nil
+	return hub, nil
 }
@@ -0,0 +1,204 @@
+/*
+kwite_class.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package v1beta2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GetKwiteClass returns the class named by spec.kwiteClassName or, when no
+// class is named, the default class. It returns nil when the kwite names no
+// class and there is no default class.
+func (r *Kwite) GetKwiteClass(ctx context.Context, c client.Reader) (*KwiteClass, error) {
+	if r.Spec.KwiteClassName != "" {
+		class := &KwiteClass{}
+		if err := c.Get(ctx, types.NamespacedName{Name: r.Spec.KwiteClassName}, class); err != nil {
+			return nil, err
+		}
+		return class, nil
+	}
+
+	var classes KwiteClassList
+	if err := c.List(ctx, &classes); err != nil {
+		return nil, err
+	}
+
+	var defaults []string
+	var class *KwiteClass
+	for i := range classes.Items {
+		if classes.Items[i].IsDefault() {
+			defaults = append(defaults, classes.Items[i].Name)
+			class = &classes.Items[i]
+		}
+	}
+	if len(defaults) > 1 {
+		return nil, fmt.Errorf("%d kwite classes are marked default: %s", len(defaults), strings.Join(defaults, ", "))
+	}
+	return class, nil
+}
+
+// ApplyDefaults fills the fields the kwite leaves unset, first from the
+// class (which may be nil) and then from the built-in defaults.
+func (r *Kwite) ApplyDefaults(class *KwiteClass) {
+	// the kwite's own limits come before any default request
+	requestLimits(&r.Spec.Resources)
+
+	if class != nil {
+		r.applyClass(&class.Spec)
+	}
+
+	r.ApplyFixedDefaults()
+
+	if r.Spec.Image == "" {
+		r.Spec.Image = "kwite:latest"
+	}
+
+	if r.Spec.Scaling.MinReplicas <= 0 {
+		r.Spec.Scaling.MinReplicas = 1
+	}
+
+	if r.Spec.Scaling.MaxReplicas <= 0 {
+		r.Spec.Scaling.MaxReplicas = r.Spec.Scaling.MinReplicas
+	}
+
+	defaultRequests(&r.Spec.Resources, corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("200m"),
+		corev1.ResourceMemory: resource.MustParse("64Mi"),
+	})
+
+	if r.Spec.Scaling.TargetCPU == 0 {
+		r.Spec.Scaling.TargetCPU = 80
+	}
+
+	if r.Spec.ImagePullSecrets == nil {
+		r.Spec.ImagePullSecrets = []corev1.LocalObjectReference{}
+	}
+
+	if r.Spec.SecurityContext == nil {
+		nonRoot := true
+		readOnly := true
+		allowEscalate := false
+		var user int64 = 65534
+		r.Spec.SecurityContext = &corev1.SecurityContext{
+			RunAsNonRoot:             &nonRoot,
+			ReadOnlyRootFilesystem:   &readOnly,
+			AllowPrivilegeEscalation: &allowEscalate,
+			RunAsUser:                &user,
+		}
+	}
+}
+
+// ApplyFixedDefaults fills the unset fields no class sets with the built-in
+// defaults. These alone may be stored in the kwite, as storing a value a
+// class sets would hide later changes to the class.
+func (r *Kwite) ApplyFixedDefaults() {
+	if r.Spec.Exposure.Url == "" {
+		r.Spec.Exposure.Url = "/"
+	}
+
+	if r.Spec.Exposure.Port == 0 {
+		r.Spec.Exposure.Port = 8080
+	}
+
+	if r.Spec.Exposure.Public == nil {
+		r.Spec.Exposure.Public = new(bool)
+	}
+
+	if r.Spec.Scaling.Autoscaling == nil {
+		autoscaling := true
+		r.Spec.Scaling.Autoscaling = &autoscaling
+	}
+}
+
+// Request the limit of every resource with a limit and no request, as the
+// API server would.
+func requestLimits(res *corev1.ResourceRequirements) {
+	for name, limit := range res.Limits {
+		if _, ok := res.Requests[name]; ok {
+			continue
+		}
+		if res.Requests == nil {
+			res.Requests = make(corev1.ResourceList)
+		}
+		res.Requests[name] = limit.DeepCopy()
+	}
+}
+
+// Add the default request of every resource the requirements leave
+// unrequested. A resource with a limit below its default requests the limit
+// instead, so the request never exceeds the limit.
+func defaultRequests(res *corev1.ResourceRequirements, defaults corev1.ResourceList) {
+	for name, q := range defaults {
+		if _, ok := res.Requests[name]; ok {
+			continue
+		}
+		if limit, ok := res.Limits[name]; ok && limit.Cmp(q) < 0 {
+			q = limit
+		}
+		if res.Requests == nil {
+			res.Requests = make(corev1.ResourceList)
+		}
+		res.Requests[name] = q.DeepCopy()
+	}
+}
+
+// Add the default limit of every resource the requirements leave unlimited,
+// except where the request already exceeds the default.
+func defaultLimits(res *corev1.ResourceRequirements, defaults corev1.ResourceList) {
+	for name, q := range defaults {
+		if _, ok := res.Limits[name]; ok {
+			continue
+		}
+		if request, ok := res.Requests[name]; ok && request.Cmp(q) > 0 {
+			continue
+		}
+		if res.Limits == nil {
+			res.Limits = make(corev1.ResourceList)
+		}
+		res.Limits[name] = q.DeepCopy()
+	}
+}
+
+// Fill the fields the kwite leaves unset from the class.
+func (r *Kwite) applyClass(spec *KwiteClassSpec) {
+	if r.Spec.Image == "" {
+		r.Spec.Image = spec.Image
+	}
+
+	if len(r.Spec.ImagePullSecrets) == 0 && len(spec.ImagePullSecrets) > 0 {
+		r.Spec.ImagePullSecrets = append([]corev1.LocalObjectReference{}, spec.ImagePullSecrets...)
+	}
+
+	if r.Spec.SecurityContext == nil && spec.SecurityContext != nil {
+		r.Spec.SecurityContext = spec.SecurityContext.DeepCopy()
+	}
+
+	// limits first, so the class requests are held to them too
+	defaultLimits(&r.Spec.Resources, spec.Resources.Limits)
+	defaultRequests(&r.Spec.Resources, spec.Resources.Requests)
+
+	if r.Spec.Scaling.MinReplicas <= 0 {
+		r.Spec.Scaling.MinReplicas = spec.Scaling.MinReplicas
+	}
+
+	if r.Spec.Scaling.MaxReplicas <= 0 {
+		r.Spec.Scaling.MaxReplicas = spec.Scaling.MaxReplicas
+	}
+
+	if r.Spec.Scaling.TargetCPU == 0 {
+		r.Spec.Scaling.TargetCPU = spec.Scaling.TargetCPU
+	}
+}
@@ -0,0 +1,54 @@
+/*
+kwite_conditions.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package v1beta2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetCondition returns the condition of the given type, or nil if not set.
+func (s *KwiteStatus) GetCondition(t KwiteConditionType) *KwiteCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == t {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsConditionTrue reports whether the condition of the given type is True.
+func (s *KwiteStatus) IsConditionTrue(t KwiteConditionType) bool {
+	c := s.GetCondition(t)
+	return c != nil && c.Status == corev1.ConditionTrue
+}
+
+// SetCondition adds or replaces the condition of the same type. The
+// transition time only moves when the status actually changes.
+func (s *KwiteStatus) SetCondition(c KwiteCondition) {
+	existing := s.GetCondition(c.Type)
+	if existing == nil {
+		if c.LastTransitionTime.IsZero() {
+			c.LastTransitionTime = metav1.Now()
+		}
+		s.Conditions = append(s.Conditions, c)
+		return
+	}
+
+	if existing.Status != c.Status {
+		existing.Status = c.Status
+		existing.LastTransitionTime = c.LastTransitionTime
+		if existing.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = metav1.Now()
+		}
+	}
+	existing.Reason = c.Reason
+	existing.Message = c.Message
+	existing.ObservedGeneration = c.ObservedGeneration
+}
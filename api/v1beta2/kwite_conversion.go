@@ -0,0 +1,13 @@
+/*
+kwite_conversion.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package v1beta2
+
+// Hub marks v1beta2 as the conversion hub (and storage version) for Kwite.
+// All other versions convert to and from this one.
+func (*Kwite) Hub() {}
@@ -0,0 +1,96 @@
+/*
+kwite_files.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package v1beta2
+
+import (
+	"strings"
+)
+
+const (
+	// MaxConfigMapSize is the most data the API server accepts in a single
+	// ConfigMap, the same limit it applies to Secrets.
+	MaxConfigMapSize = 1024 * 1024
+)
+
+// Files under the configs mount the kwite instances read their configuration
+// from, which spec.files may not replace. The route keys are held back for
+// kwite releases serving several routes.
+var reservedFileNames = map[string]bool{
+	"url":      true,
+	"template": true,
+	"ready":    true,
+	"alive":    true,
+	"rewrite":  true,
+	"routes":   true,
+}
+
+// IsReservedFileName reports whether the name is taken by the kwite
+// configuration written to the configs mount.
+func IsReservedFileName(name string) bool {
+	return reservedFileNames[name] || strings.HasPrefix(name, "route-")
+}
+
+// Size returns the number of bytes the file takes in a ConfigMap
+func (f *KwiteFile) Size() int {
+	return len(f.Text) + len(f.Binary)
+}
+
+// ConfigData returns the ConfigMap data the kwite instances read their
+// route and files from, with the definitions of the libraries appended to
+// every template. The kwite server reads the url, template, ready and alive
+// keys of a single route. Binary files are returned separately as the
+// binary data. Templates loaded from other objects must be resolved into
+// inline templates first.
+func (r *Kwite) ConfigData(libs []KwiteTemplateLibrary) (map[string]string, map[string][]byte) {
+	// the kwite instances parse each file once, so every template carries
+	// the library definitions after its own text.
+	defs := LibraryDefinitions(libs)
+
+	route := r.GetRoutes()[0]
+	d := map[string]string{
+		"url":      route.Path,
+		"template": route.Template.Inline + defs,
+		"ready":    route.Ready.Inline + defs,
+		"alive":    route.Alive.Inline + defs,
+	}
+
+	var bd map[string][]byte
+	for name, f := range r.Spec.Files {
+		if len(f.Binary) == 0 {
+			d[name] = f.Text
+			continue
+		}
+		if bd == nil {
+			bd = make(map[string][]byte)
+		}
+		bd[name] = f.Binary
+	}
+
+	return d, bd
+}
+
+// DataSize returns the number of bytes the data and binary data take in a
+// ConfigMap, counted as the API server counts them against its limit.
+func DataSize(d map[string]string, bd map[string][]byte) int {
+	size := 0
+	for k, v := range d {
+		size += len(k) + len(v)
+	}
+	for k, v := range bd {
+		size += len(k) + len(v)
+	}
+	return size
+}
+
+// ConfigSize returns the number of bytes the ConfigMap data of the kwite
+// takes with the given libraries. Templates loaded from other objects are
+// not counted, nor is the rewrite map.
+func (r *Kwite) ConfigSize(libs []KwiteTemplateLibrary) int {
+	return DataSize(r.ConfigData(libs))
+}
@@ -0,0 +1,42 @@
+/*
+kwite_podtemplate.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package v1beta2
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// PatchPodTemplate applies spec.podTemplate to the pod template as a
+// strategic merge patch. The pod template is left alone when there is no
+// patch or the patch fails.
+func (r *Kwite) PatchPodTemplate(tmpl *corev1.PodTemplateSpec) error {
+	if r.Spec.PodTemplate == nil || len(r.Spec.PodTemplate.Raw) == 0 {
+		return nil
+	}
+
+	orig, err := json.Marshal(tmpl)
+	if err != nil {
+		return err
+	}
+
+	patched, err := strategicpatch.StrategicMergePatch(orig, r.Spec.PodTemplate.Raw, corev1.PodTemplateSpec{})
+	if err != nil {
+		return err
+	}
+
+	result := corev1.PodTemplateSpec{}
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return err
+	}
+	*tmpl = result
+	return nil
+}
@@ -0,0 +1,155 @@
+/*
+kwite_templates.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package v1beta2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"text/template"
+	"text/template/parse"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// VisitTemplates calls fn for every template of the kwite along with its
+// field path and a short name, so callers need not know the spec layout.
+func (r *Kwite) VisitTemplates(fn func(fldPath *field.Path, name string, t *KwiteTemplate)) {
+	fldPath := field.NewPath("spec")
+
+	fn(fldPath.Child("template"), "template", &r.Spec.Template)
+
+	probesPath := fldPath.Child("probes")
+	fn(probesPath.Child("ready"), "ready", &r.Spec.Probes.Ready.KwiteTemplate)
+	fn(probesPath.Child("alive"), "alive", &r.Spec.Probes.Alive.KwiteTemplate)
+
+	routesPath := fldPath.Child("routes")
+	for i := range r.Spec.Routes {
+		route := &r.Spec.Routes[i]
+		routePath := routesPath.Index(i)
+		fn(routePath.Child("template"), "template", &route.Template)
+		if route.Ready != nil {
+			fn(routePath.Child("ready"), "ready", route.Ready)
+		}
+		if route.Alive != nil {
+			fn(routePath.Child("alive"), "alive", route.Alive)
+		}
+	}
+}
+
+// GetLibraries returns the template libraries the kwite references, in order.
+func (r *Kwite) GetLibraries(ctx context.Context, c client.Reader) ([]KwiteTemplateLibrary, error) {
+	libs := make([]KwiteTemplateLibrary, 0, len(r.Spec.Libraries))
+	for _, ref := range r.Spec.Libraries {
+		lib := KwiteTemplateLibrary{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: ref.Name}, &lib); err != nil {
+			return nil, err
+		}
+		libs = append(libs, lib)
+	}
+	return libs, nil
+}
+
+// Return the names of templates invoked but not defined in the template set.
+func undefinedTemplates(tmpl *template.Template) []string {
+	refs := make(map[string]bool)
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			templateReferences(t.Tree.Root, refs)
+		}
+	}
+
+	var undefined []string
+	for name := range refs {
+		if tmpl.Lookup(name) == nil {
+			undefined = append(undefined, name)
+		}
+	}
+	sort.Strings(undefined)
+	return undefined
+}
+
+// Collect the names of the templates invoked under the parse tree node.
+func templateReferences(node parse.Node, refs map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			templateReferences(c, refs)
+		}
+	case *parse.IfNode:
+		templateReferences(n.List, refs)
+		templateReferences(n.ElseList, refs)
+	case *parse.RangeNode:
+		templateReferences(n.List, refs)
+		templateReferences(n.ElseList, refs)
+	case *parse.WithNode:
+		templateReferences(n.List, refs)
+		templateReferences(n.ElseList, refs)
+	case *parse.TemplateNode:
+		refs[n.Name] = true
+	}
+}
+
+// Return whether an optional marker is set and true
+func isOptional(optional *bool) bool {
+	return optional != nil && *optional
+}
+
+// Resolve returns the template text held by the referenced key. A missing
+// optional ConfigMap, Secret or key resolves to an empty template.
+func (s *TemplateSource) Resolve(ctx context.Context, c client.Reader, namespace string) (string, error) {
+	switch {
+	case s.ConfigMapKeyRef != nil:
+		ref := s.ConfigMapKeyRef
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, cm); err != nil {
+			if apierrors.IsNotFound(err) && isOptional(ref.Optional) {
+				return "", nil
+			}
+			return "", err
+		}
+		if v, ok := cm.Data[ref.Key]; ok {
+			return v, nil
+		}
+		if v, ok := cm.BinaryData[ref.Key]; ok {
+			return string(v), nil
+		}
+		if isOptional(ref.Optional) {
+			return "", nil
+		}
+		return "", fmt.Errorf("key %s not found in ConfigMap %s/%s", ref.Key, namespace, ref.Name)
+
+	case s.SecretKeyRef != nil:
+		ref := s.SecretKeyRef
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+			if apierrors.IsNotFound(err) && isOptional(ref.Optional) {
+				return "", nil
+			}
+			return "", err
+		}
+		if v, ok := secret.Data[ref.Key]; ok {
+			return string(v), nil
+		}
+		if isOptional(ref.Optional) {
+			return "", nil
+		}
+		return "", fmt.Errorf("key %s not found in Secret %s/%s", ref.Key, namespace, ref.Name)
+	}
+
+	return "", errors.New("neither configMapKeyRef nor secretKeyRef is set")
+}
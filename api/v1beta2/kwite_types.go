@@ -0,0 +1,508 @@
+/*
+kwite_types.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package v1beta2
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var (
+	ControllerName = "Kwite"
+)
+
+// KwiteExposure defines how the kwite instances are reached
+type KwiteExposure struct {
+	// +kubebuilder:validation:MinLength=0
+
+	// The URL to handle in the kwite instances, default "/"
+	Url string `json:"url"`
+
+	// port on which to expose the Url, default is 8080
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// Whether the url is public (i.e., needs an ingress), default false
+	// +optional
+	Public *bool `json:"public,omitempty"`
+}
+
+// KwiteScaling defines the replica bounds and autoscaling target
+type KwiteScaling struct {
+	// Whether a HorizontalPodAutoscaler manages the replica count, default true
+	// +optional
+	Autoscaling *bool `json:"autoscaling,omitempty"`
+
+	// +kubebuilder:validation:Minimum=0
+
+	// The number of page handler replicas when autoscaling is off, default
+	// is the minimum number of replicas. Set by "kubectl scale".
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+
+	// The minimum number of page hander replicas, default is 1 (one)
+	// +optional
+	MinReplicas int32 `json:"minReplicas,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+
+	// The maximum number of page hander replicas, default is the minimum
+	// +optional
+	MaxReplicas int32 `json:"maxReplicas,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+
+	// HorizontalPodAutoscaler CPU target utilization per pod, default is 80
+	// +optional
+	TargetCPU int32 `json:"targetCPU,omitempty"`
+}
+
+// AutoscalingEnabled reports whether a HorizontalPodAutoscaler manages replicas
+func (s *KwiteScaling) AutoscalingEnabled() bool {
+	return s.Autoscaling == nil || *s.Autoscaling
+}
+
+// DesiredReplicas returns the replica count to use when autoscaling is off
+func (s *KwiteScaling) DesiredReplicas() int32 {
+	if s.Replicas != nil {
+		return *s.Replicas
+	}
+	return s.MinReplicas
+}
+
+// TemplateSource selects a template held in a ConfigMap or Secret key in the
+// kwite namespace. Exactly one of the references must be set.
+type TemplateSource struct {
+	// Selects a key of a ConfigMap
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// Selects a key of a Secret
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// KwiteTemplate holds a template executed by the kwite instances, either
+// inline or loaded from a ConfigMap or Secret
+type KwiteTemplate struct {
+	// +kubebuilder:validation:MinLength=0
+
+	// The template text
+	// +optional
+	Inline string `json:"inline,omitempty"`
+
+	// Load the template text from a ConfigMap or Secret key instead
+	// +optional
+	From *TemplateSource `json:"from,omitempty"`
+}
+
+// KwiteProbeSettings defines the path and timing of a probe of the kwite
+// instance Pods. Unset fields keep the operator defaults.
+type KwiteProbeSettings struct {
+	// The HTTP path to probe, default is the kwiteready (readiness) or
+	// kwitealive (startup and liveness) path of the route
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// +kubebuilder:validation:Minimum=0
+
+	// Seconds after the container starts before the probe is first requested
+	// +optional
+	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+
+	// How often (in seconds) to request the probe, default is 1 for the
+	// startup probe and 3 otherwise
+	// +optional
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+
+	// Seconds after which the probe request times out, default is 1
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+
+	// Consecutive successes for the probe to be considered successful after
+	// having failed, default is 1. Must be 1 for startup and liveness.
+	// +optional
+	SuccessThreshold int32 `json:"successThreshold,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+
+	// Consecutive failures for the probe to be considered failed, default is
+	// 5 for the startup probe and 3 otherwise
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// KwiteProbe defines a health probe served by the kwite instances
+type KwiteProbe struct {
+	// The template to execute when the probe is requested
+	KwiteTemplate `json:",inline"`
+
+	// How the kubelet requests the probe
+	KwiteProbeSettings `json:",inline"`
+}
+
+// KwiteProbes defines the readiness and aliveness probes
+type KwiteProbes struct {
+	// The readiness probe
+	// +optional
+	Ready KwiteProbe `json:"ready,omitempty"`
+
+	// The aliveness probe, requested by the liveness probe
+	// +optional
+	Alive KwiteProbe `json:"alive,omitempty"`
+
+	// The startup probe, which requests the aliveness template
+	// +optional
+	Startup KwiteProbeSettings `json:"startup,omitempty"`
+}
+
+// KwiteRoute defines a URL path served by the kwite instances
+type KwiteRoute struct {
+	// +kubebuilder:validation:MinLength=1
+
+	// The URL path to handle, e.g. "/about"
+	Path string `json:"path"`
+
+	// The template to execute for the path
+	Template KwiteTemplate `json:"template"`
+
+	// The readiness template for the path, default is spec.probes.ready
+	// +optional
+	Ready *KwiteTemplate `json:"ready,omitempty"`
+
+	// The aliveness template for the path, default is spec.probes.alive
+	// +optional
+	Alive *KwiteTemplate `json:"alive,omitempty"`
+}
+
+// KwiteFile holds a file placed alongside the templates under /configs.
+// At most one of text or binary may be set.
+type KwiteFile struct {
+	// The file content as text
+	// +optional
+	Text string `json:"text,omitempty"`
+
+	// The file content as base64 encoded binary data
+	// +optional
+	Binary []byte `json:"binary,omitempty"`
+}
+
+// KwiteFilesSource selects a ConfigMap or Secret in the kwite namespace whose
+// keys are placed as files under /configs. Exactly one of the references must
+// be set.
+type KwiteFilesSource struct {
+	// The ConfigMap to project
+	// +optional
+	ConfigMapRef *corev1.LocalObjectReference `json:"configMapRef,omitempty"`
+
+	// The Secret to project
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// Whether the ConfigMap or Secret may be missing
+	// +optional
+	Optional *bool `json:"optional,omitempty"`
+}
+
+// KwiteSpec defines the desired state of Kwite
+type KwiteSpec struct {
+	// Whether to stop reconciling the child resources (e.g., to hand-edit
+	// them during an incident). Status is still reported. Default false.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// The KwiteClass providing defaults for the fields left unset. When
+	// empty, the class annotated as the default class is used, if any.
+	// +optional
+	KwiteClassName string `json:"kwiteClassName,omitempty"`
+
+	// container image to use for the http(s) server, default is the class
+	// image or kwite:latest
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Image pull secrets name for container pulls.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// The security context for kwite instance Pods, default is no specified context
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+
+	// How the Deployment replaces kwite instance Pods when the kwite changes,
+	// default is a rolling update with 25% max surge and max unavailable
+	// +optional
+	Strategy appsv1.DeploymentStrategy `json:"strategy,omitempty"`
+
+	// +kubebuilder:validation:Minimum=0
+
+	// Seconds a new kwite instance Pod must be ready before it is available
+	// +optional
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+
+	// Seconds a rollout may make no progress before it is reported as
+	// failed, default is 600
+	// +optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// +kubebuilder:validation:Minimum=0
+
+	// The number of old ReplicaSets kept for rollback, default is 10
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// Node labels a node must have to run kwite instance Pods
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations of the kwite instance Pods
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Scheduling constraints of the kwite instance Pods. When neither affinity
+	// nor topologySpreadConstraints is set and minReplicas is more than one,
+	// the Pods prefer to run on different nodes and zones.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// How the kwite instance Pods spread across topology domains
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// The priority class of the kwite instance Pods
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Environment variables for the kwite container
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Sources (ConfigMaps and Secrets) to populate the kwite container
+	// environment from
+	// +optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+
+	// How the URL is exposed
+	Exposure KwiteExposure `json:"exposure"`
+
+	// Replica bounds and autoscaling settings
+	// +optional
+	Scaling KwiteScaling `json:"scaling,omitempty"`
+
+	// Compute resource requests and limits (e.g., cpu, memory and
+	// ephemeral-storage) for each kwite instance, default requests are
+	// "200m" cpu and "64Mi" memory
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// The readiness and aliveness probes
+	// +optional
+	Probes KwiteProbes `json:"probes,omitempty"`
+
+	// The template to execute for the kwite instances
+	Template KwiteTemplate `json:"template"`
+
+	// +kubebuilder:validation:MaxItems=1
+
+	// The URL path to serve with its own template. When empty, the kwite
+	// serves spec.exposure.url with spec.template. The kwite server reads a
+	// single route, so at most one may be given.
+	// +optional
+	Routes []KwiteRoute `json:"routes,omitempty"`
+
+	// Files (e.g., CSS, images and JSON fixtures) placed alongside the
+	// templates under /configs, keyed by file name
+	// +optional
+	Files map[string]KwiteFile `json:"files,omitempty"`
+
+	// ConfigMaps and Secrets whose keys are placed as files under /configs
+	// +optional
+	FilesFrom []KwiteFilesSource `json:"filesFrom,omitempty"`
+
+	// +kubebuilder:pruning:PreserveUnknownFields
+
+	// A strategic merge patch applied to the generated pod template, e.g. to
+	// add sidecars, volumes, annotations or scheduling constraints. The kwite
+	// container is named "kwite".
+	// +optional
+	PodTemplate *runtime.RawExtension `json:"podTemplate,omitempty"`
+
+	// Template libraries in the kwite namespace whose named templates are
+	// available to every template of the kwite
+	// +optional
+	Libraries []corev1.LocalObjectReference `json:"libraries,omitempty"`
+}
+
+// KwiteConditionType is a valid value for KwiteCondition.Type
+type KwiteConditionType string
+
+const (
+	// KwiteAvailable means the minimum number of kwite instances are ready.
+	KwiteAvailable KwiteConditionType = "Available"
+
+	// KwiteProgressing means the kwite Deployment is rolling out.
+	KwiteProgressing KwiteConditionType = "Progressing"
+
+	// KwiteDegraded means the kwite is failing to reach or keep its desired state.
+	KwiteDegraded KwiteConditionType = "Degraded"
+
+	// KwiteTemplateValid means every template of the kwite parses.
+	KwiteTemplateValid KwiteConditionType = "TemplateValid"
+
+	// KwiteChildResourcesReconciled means every child resource was reconciled.
+	KwiteChildResourcesReconciled KwiteConditionType = "ChildResourcesReconciled"
+
+	// KwiteSuspended means the child resources are left alone.
+	KwiteSuspended KwiteConditionType = "Suspended"
+
+	// KwiteFieldConflict means another field manager had changed fields of
+	// the child resources that the operator sets.
+	KwiteFieldConflict KwiteConditionType = "FieldConflict"
+)
+
+// KwiteCondition describes the state of a kwite at a certain point
+type KwiteCondition struct {
+	// Type of the condition
+	Type KwiteConditionType `json:"type"`
+
+	// +kubebuilder:validation:Enum=True;False;Unknown
+
+	// Status of the condition, one of True, False or Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// The .metadata.generation the condition was set from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Last time the condition transitioned from one status to another
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// The CamelCase reason for the condition's last transition
+	Reason string `json:"reason"`
+
+	// A human readable message with details about the transition
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// GetRoutes returns the routes the kwite serves, with the readiness and
+// aliveness templates resolved. A kwite without spec.routes serves a single
+// route built from spec.exposure.url and spec.template.
+func (r *Kwite) GetRoutes() []KwiteRoute {
+	ready := r.Spec.Probes.Ready.KwiteTemplate
+	alive := r.Spec.Probes.Alive.KwiteTemplate
+
+	if len(r.Spec.Routes) == 0 {
+		return []KwiteRoute{
+			{
+				Path:     r.Spec.Exposure.Url,
+				Template: r.Spec.Template,
+				Ready:    &ready,
+				Alive:    &alive,
+			},
+		}
+	}
+
+	routes := make([]KwiteRoute, 0, len(r.Spec.Routes))
+	for _, route := range r.Spec.Routes {
+		rt := *route.DeepCopy()
+		if rt.Ready == nil {
+			rt.Ready = ready.DeepCopy()
+		}
+		if rt.Alive == nil {
+			rt.Alive = alive.DeepCopy()
+		}
+		routes = append(routes, rt)
+	}
+	return routes
+}
+
+// KwiteStatus defines the observed state of Kwite
+type KwiteStatus struct {
+	// The most recent generation observed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The latest observations of the kwite's state
+	// +optional
+	Conditions []KwiteCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// The service address on which the URL is exposed
+	Address string `json:"address,omitempty"`
+
+	// The number of ready replicas HPA is requesting
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// The number of replicas running the latest kwite Pod template
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// The number of replicas available (ready for at least minReadySeconds)
+	// +optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+
+	// The total number of replicas HPA is requesting
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+
+	// True if the minimum number of replicas are ready
+	Ready bool `json:"ready"`
+
+	// The label selector of the kwite instance Pods, for the scale subresource
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// The hash of the generated ConfigMap content the Pod template carries
+	// +optional
+	ConfigHash string `json:"configHash,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.scaling.replicas,statuspath=.status.readyReplicas,selectorpath=.status.selector
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="URL",type="string",JSONPath=".spec.exposure.url"
+// +kubebuilder:printcolumn:name="Address",type="string",JSONPath=".status.address"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Desired",type="integer",JSONPath=".status.desiredReplicas"
+// +kubebuilder:printcolumn:name="Up-to-date",type="integer",JSONPath=".status.updatedReplicas"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Kwite is the Schema for the kwites API
+type Kwite struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KwiteSpec   `json:"spec,omitempty"`
+	Status KwiteStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KwiteList contains a list of Kwite
+type KwiteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Kwite `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Kwite{}, &KwiteList{})
+}
@@ -0,0 +1,477 @@
+/*
+kwite_webhook.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package v1beta2
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/tdhite/kwite/pkg/funcs"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	validationutils "k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var kwitelog = logf.Log.WithName("kwite-resource")
+
+// Reads objects referenced by kwites (e.g., template sources) for validation.
+// Unset unless the webhooks are setup with a manager.
+var webhookReader client.Reader
+
+func (r *Kwite) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookReader = mgr.GetAPIReader()
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-web-kwite-site-v1beta2-kwite,mutating=true,failurePolicy=fail,groups=web.kwite.site,resources=kwites,verbs=create;update,versions=v1beta2,name=mkwite.v1beta2.kwite.site
+
+var _ webhook.Defaulter = &Kwite{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *Kwite) Default() {
+	kwitelog.Info("default", "name", r.Name)
+
+	// class values are left to the reconciler, so the kwite follows later
+	// changes to its class
+	r.ApplyFixedDefaults()
+	if webhookReader == nil {
+		return
+	}
+
+	// a kwite naming no class is given the default class, if any
+	class, err := r.GetKwiteClass(context.Background(), webhookReader)
+	if err != nil {
+		kwitelog.Error(err, "unable to load kwite class", "name", r.Name)
+	} else if class != nil {
+		r.Spec.KwiteClassName = class.Name
+	}
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-web-kwite-site-v1beta2-kwite,mutating=false,failurePolicy=fail,groups=web.kwite.site,resources=kwites,versions=v1beta2,name=vkwite.v1beta2.kwite.site
+
+var _ webhook.Validator = &Kwite{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Kwite) ValidateCreate() error {
+	kwitelog.Info("validate create", "name", r.Name)
+
+	return r.validateKwite(webhookReader)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Kwite) ValidateUpdate(old runtime.Object) error {
+	kwitelog.Info("validate update", "name", r.Name)
+
+	// a kwite being deleted may well outlive what it references, and must
+	// still be able to drop its finalizer
+	if !r.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	// the spec was accepted before, so metadata and finalizer updates go
+	// through even when later validation or referenced objects would fail it
+	if oldKwite, ok := old.(*Kwite); ok && apiequality.Semantic.DeepEqual(oldKwite.Spec, r.Spec) {
+		return nil
+	}
+	return r.validateKwite(webhookReader)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Kwite) ValidateDelete() error {
+	kwitelog.Info("validate delete", "name", r.Name)
+
+	return nil
+}
+
+// Validate the kwite object. Referenced objects are only checked when a
+// reader is given.
+func (r *Kwite) validateKwite(reader client.Reader) error {
+	var allErrs field.ErrorList
+	allErrs = r.validateKwiteName(allErrs)
+	allErrs = r.validateKwiteSpec(reader, allErrs)
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: "web.kwite.site", Kind: ControllerName},
+		r.Name, allErrs)
+}
+
+// Validate that the Kwite name conforms to the rules on object fields
+func (r *Kwite) validateKwiteName(allErrs field.ErrorList) field.ErrorList {
+	if len(r.ObjectMeta.Name) > validationutils.DNS1035LabelMaxLength {
+		// The kwite name length is 63 character like all Kubernetes objects
+		// (which must fit in a DNS subdomain).
+		fe := field.Invalid(field.NewPath("metadata").Child("name"), r.Name, "must be no more than 63 characters")
+		allErrs = append(allErrs, fe)
+	}
+	return allErrs
+}
+
+// Validate the Kwite Spec object
+func (r *Kwite) validateKwiteSpec(reader client.Reader, allErrs field.ErrorList) field.ErrorList {
+	// The field helpers from the kubernetes API machinery help us return nicely
+	// structured validation errors.
+
+	fldPath := field.NewPath("spec")
+
+	allErrs = r.validateResources(fldPath.Child("resources"), allErrs)
+	allErrs = r.validateProbes(fldPath.Child("probes"), allErrs)
+	allErrs = r.validateStrategy(fldPath, allErrs)
+
+	allErrs = r.validateRoutes(fldPath.Child("routes"), allErrs)
+	allErrs = r.validateKwiteClass(reader, fldPath.Child("kwiteClassName"), allErrs)
+	allErrs = r.validateEnv(fldPath, allErrs)
+	allErrs = r.validateFiles(fldPath, allErrs)
+	allErrs = r.validatePodTemplate(fldPath.Child("podTemplate"), allErrs)
+
+	libs, allErrs := r.validateLibraries(reader, fldPath.Child("libraries"), allErrs)
+	allErrs = append(allErrs, r.ValidateTemplates(libs)...)
+	allErrs = r.validateTemplateSources(reader, libs, allErrs)
+	allErrs = r.validateConfigSize(libs, fldPath.Child("files"), allErrs)
+
+	return allErrs
+}
+
+// Validate that there is at most one route and its path is absolute
+func (r *Kwite) validateRoutes(fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	// the kwite server reads the url and templates of one route only
+	if len(r.Spec.Routes) > 1 {
+		allErrs = append(allErrs, field.TooMany(fldPath, len(r.Spec.Routes), 1))
+	}
+
+	for i, route := range r.Spec.Routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child("path"), route.Path, "must begin with '/'"))
+		}
+	}
+	return allErrs
+}
+
+// Validate the environment variable names and that each envFrom source
+// names exactly one of a ConfigMap or Secret.
+func (r *Kwite) validateEnv(fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	envPath := fldPath.Child("env")
+	for i, env := range r.Spec.Env {
+		for _, msg := range validationutils.IsEnvVarName(env.Name) {
+			allErrs = append(allErrs, field.Invalid(envPath.Index(i).Child("name"), env.Name, msg))
+		}
+	}
+
+	envFromPath := fldPath.Child("envFrom")
+	for i, envFrom := range r.Spec.EnvFrom {
+		if (envFrom.ConfigMapRef == nil) == (envFrom.SecretRef == nil) {
+			allErrs = append(allErrs, field.Invalid(envFromPath.Index(i), "", "exactly one of configMapRef or secretRef must be set"))
+		}
+		if envFrom.Prefix != "" {
+			for _, msg := range validationutils.IsEnvVarName(envFrom.Prefix) {
+				allErrs = append(allErrs, field.Invalid(envFromPath.Index(i).Child("prefix"), envFrom.Prefix, msg))
+			}
+		}
+	}
+	return allErrs
+}
+
+// Validate the file names and sources, and that the files fit in the kwite
+// ConfigMap along with the templates.
+func (r *Kwite) validateFiles(fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	filesPath := fldPath.Child("files")
+
+	names := make([]string, 0, len(r.Spec.Files))
+	for name := range r.Spec.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := r.Spec.Files[name]
+		for _, msg := range validationutils.IsConfigMapKey(name) {
+			allErrs = append(allErrs, field.Invalid(filesPath.Key(name), name, msg))
+		}
+		if IsReservedFileName(name) {
+			allErrs = append(allErrs, field.Invalid(filesPath.Key(name), name, "is reserved for the kwite configuration"))
+		}
+		if f.Text != "" && len(f.Binary) > 0 {
+			allErrs = append(allErrs, field.Invalid(filesPath.Key(name), name, "at most one of text or binary may be set"))
+		}
+	}
+
+	filesFromPath := fldPath.Child("filesFrom")
+	for i, source := range r.Spec.FilesFrom {
+		if (source.ConfigMapRef == nil) == (source.SecretRef == nil) {
+			allErrs = append(allErrs, field.Invalid(filesFromPath.Index(i), "", "exactly one of configMapRef or secretRef must be set"))
+		}
+	}
+	return allErrs
+}
+
+// Validate that the pod template patch applies and keeps the kwite container
+// and the labels selecting the kwite pods.
+func (r *Kwite) validatePodTemplate(fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	if r.Spec.PodTemplate == nil {
+		return allErrs
+	}
+
+	tmpl := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{"kwite": r.Name},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "kwite", Image: r.Spec.Image}},
+		},
+	}
+	if err := r.PatchPodTemplate(&tmpl); err != nil {
+		return append(allErrs, field.Invalid(fldPath, "", err.Error()))
+	}
+
+	if tmpl.Labels["kwite"] != r.Name {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("metadata", "labels").Key("kwite"), "may not be changed"))
+	}
+	found := false
+	for _, c := range tmpl.Spec.Containers {
+		if c.Name == "kwite" {
+			found = true
+		}
+	}
+	if !found {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("spec", "containers"), "may not remove the kwite container"))
+	}
+	return allErrs
+}
+
+// Validate that the ConfigMap written for the kwite, the library
+// definitions included, fits within the ConfigMap limit.
+func (r *Kwite) validateConfigSize(libs []KwiteTemplateLibrary, filesPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	if size := r.ConfigSize(libs); size > MaxConfigMapSize {
+		allErrs = append(allErrs, field.Invalid(filesPath, size,
+			fmt.Sprintf("combined size of files and templates must be no more than the ConfigMap limit of %d bytes", MaxConfigMapSize)))
+	}
+	return allErrs
+}
+
+// Validate that the named kwite class exists, when given a reader.
+func (r *Kwite) validateKwiteClass(reader client.Reader, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	if reader == nil || r.Spec.KwiteClassName == "" {
+		return allErrs
+	}
+
+	class := KwiteClass{}
+	if err := reader.Get(context.Background(), types.NamespacedName{Name: r.Spec.KwiteClassName}, &class); err != nil {
+		if apierrors.IsNotFound(err) {
+			allErrs = append(allErrs, field.NotFound(fldPath, r.Spec.KwiteClassName))
+		} else {
+			allErrs = append(allErrs, field.InternalError(fldPath, err))
+		}
+	}
+	return allErrs
+}
+
+// Validate that the referenced template libraries exist, returning those
+// loaded. Nothing is loaded without a reader.
+func (r *Kwite) validateLibraries(reader client.Reader, fldPath *field.Path, allErrs field.ErrorList) ([]KwiteTemplateLibrary, field.ErrorList) {
+	if reader == nil {
+		return nil, allErrs
+	}
+
+	libs := make([]KwiteTemplateLibrary, 0, len(r.Spec.Libraries))
+	for i, ref := range r.Spec.Libraries {
+		lib := KwiteTemplateLibrary{}
+		key := types.NamespacedName{Namespace: r.Namespace, Name: ref.Name}
+		if err := reader.Get(context.Background(), key, &lib); err != nil {
+			if apierrors.IsNotFound(err) {
+				allErrs = append(allErrs, field.NotFound(fldPath.Index(i), ref.Name))
+			} else {
+				allErrs = append(allErrs, field.InternalError(fldPath.Index(i), err))
+			}
+			continue
+		}
+		libs = append(libs, lib)
+	}
+	return libs, allErrs
+}
+
+// ValidateTemplates checks that every inline template of the kwite parses
+// together with the given template libraries. Invoking an undefined template
+// is only reported when all of the kwite libraries are given. The controller
+// uses it as well, since the webhooks may not be enabled.
+func (r *Kwite) ValidateTemplates(libs []KwiteTemplateLibrary) field.ErrorList {
+	var allErrs field.ErrorList
+
+	r.VisitTemplates(func(fldPath *field.Path, name string, t *KwiteTemplate) {
+		if fe := r.validateTemplate(fldPath.Child("inline"), name, t.Inline, libs); fe != nil {
+			allErrs = append(allErrs, fe)
+		}
+	})
+
+	return allErrs
+}
+
+// Validate that template sources are well formed and, when given a reader,
+// that the referenced objects exist and hold parsable templates.
+func (r *Kwite) validateTemplateSources(reader client.Reader, libs []KwiteTemplateLibrary, allErrs field.ErrorList) field.ErrorList {
+	r.VisitTemplates(func(fldPath *field.Path, name string, t *KwiteTemplate) {
+		if t.From == nil {
+			return
+		}
+
+		fromPath := fldPath.Child("from")
+		if t.Inline != "" {
+			allErrs = append(allErrs, field.Forbidden(fromPath, "may not be set together with inline"))
+			return
+		}
+		if (t.From.ConfigMapKeyRef == nil) == (t.From.SecretKeyRef == nil) {
+			allErrs = append(allErrs, field.Invalid(fromPath, "", "exactly one of configMapKeyRef or secretKeyRef must be set"))
+			return
+		}
+		if reader == nil {
+			return
+		}
+
+		text, err := t.From.Resolve(context.Background(), reader, r.Namespace)
+		if apierrors.IsNotFound(err) {
+			allErrs = append(allErrs, field.NotFound(fromPath, err.Error()))
+		} else if err != nil {
+			allErrs = append(allErrs, field.Invalid(fromPath, "", err.Error()))
+		} else if fe := r.validateTemplate(fromPath, name, text, libs); fe != nil {
+			allErrs = append(allErrs, fe)
+		}
+	})
+
+	return allErrs
+}
+
+// Validate the rollout strategy and that the progress deadline leaves time
+// for new Pods to become available.
+func (r *Kwite) validateStrategy(fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	strategy := &r.Spec.Strategy
+	strategyPath := fldPath.Child("strategy")
+
+	switch strategy.Type {
+	case "", appsv1.RollingUpdateDeploymentStrategyType:
+		if ru := strategy.RollingUpdate; ru != nil && isZero(ru.MaxSurge) && isZero(ru.MaxUnavailable) {
+			allErrs = append(allErrs, field.Invalid(strategyPath.Child("rollingUpdate", "maxUnavailable"), 0,
+				"may not be 0 when maxSurge is 0"))
+		}
+	case appsv1.RecreateDeploymentStrategyType:
+		if strategy.RollingUpdate != nil {
+			allErrs = append(allErrs, field.Forbidden(strategyPath.Child("rollingUpdate"),
+				"may not be specified when strategy type is Recreate"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(strategyPath.Child("type"), strategy.Type,
+			[]string{string(appsv1.RecreateDeploymentStrategyType), string(appsv1.RollingUpdateDeploymentStrategyType)}))
+	}
+
+	if d := r.Spec.ProgressDeadlineSeconds; d != nil && *d <= r.Spec.MinReadySeconds {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("progressDeadlineSeconds"), *d,
+			"must be greater than minReadySeconds"))
+	}
+	return allErrs
+}
+
+// Return whether the value is set to zero, either as a count or percentage.
+func isZero(v *intstr.IntOrString) bool {
+	if v == nil {
+		return false
+	}
+	n, err := intstr.GetValueFromIntOrPercent(v, 100, true)
+	return err == nil && n == 0
+}
+
+// Validate the probe paths and that startup and liveness succeed on the
+// first success, as the kubelet requires.
+func (r *Kwite) validateProbes(fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	probes := []struct {
+		name     string
+		settings *KwiteProbeSettings
+	}{
+		{"ready", &r.Spec.Probes.Ready.KwiteProbeSettings},
+		{"alive", &r.Spec.Probes.Alive.KwiteProbeSettings},
+		{"startup", &r.Spec.Probes.Startup},
+	}
+
+	for _, p := range probes {
+		probePath := fldPath.Child(p.name)
+		if p.settings.Path != "" && !strings.HasPrefix(p.settings.Path, "/") {
+			allErrs = append(allErrs, field.Invalid(probePath.Child("path"), p.settings.Path, "must begin with '/'"))
+		}
+		if p.name != "ready" && p.settings.SuccessThreshold > 1 {
+			allErrs = append(allErrs, field.Invalid(probePath.Child("successThreshold"), p.settings.SuccessThreshold, "must be 1"))
+		}
+	}
+	return allErrs
+}
+
+// Validate that resource quantities are not negative and that no request
+// exceeds its limit.
+func (r *Kwite) validateResources(fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	limitsPath := fldPath.Child("limits")
+	for name, q := range r.Spec.Resources.Limits {
+		if fe := r.validateQuantity(limitsPath.Key(string(name)), q); fe != nil {
+			allErrs = append(allErrs, fe)
+		}
+	}
+
+	requestsPath := fldPath.Child("requests")
+	for name, q := range r.Spec.Resources.Requests {
+		if fe := r.validateQuantity(requestsPath.Key(string(name)), q); fe != nil {
+			allErrs = append(allErrs, fe)
+		}
+		if limit, ok := r.Spec.Resources.Limits[name]; ok && q.Cmp(limit) > 0 {
+			allErrs = append(allErrs, field.Invalid(requestsPath.Key(string(name)), q.String(),
+				"must be less than or equal to "+string(name)+" limit of "+limit.String()))
+		}
+	}
+	return allErrs
+}
+
+// Validate that the quantity is not negative.
+func (r *Kwite) validateQuantity(fldPath *field.Path, q resource.Quantity) *field.Error {
+	if q.Sign() < 0 {
+		return field.Invalid(fldPath, q.String(), "must be greater than or equal to 0")
+	}
+	return nil
+}
+
+// Validate that the Template within the Spec parses successfully. The
+// library definitions follow the template, just as in the kwite ConfigMap.
+func (r *Kwite) validateTemplate(fldPath *field.Path, name string, t string, libs []KwiteTemplateLibrary) *field.Error {
+	tmpl, err := template.New(name).Funcs(funcs.TextTemplateFuncs()).Parse(t + LibraryDefinitions(libs))
+	if err != nil {
+		return field.Invalid(fldPath, r.Name, err.Error())
+	}
+	if len(libs) != len(r.Spec.Libraries) {
+		return nil
+	}
+	if undefined := undefinedTemplates(tmpl); len(undefined) > 0 {
+		return field.Invalid(fldPath, r.Name, "undefined templates: "+strings.Join(undefined, ", "))
+	}
+	return nil
+}
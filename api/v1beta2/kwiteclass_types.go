@@ -0,0 +1,98 @@
+/*
+kwiteclass_types.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package v1beta2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	ClassKind = "KwiteClass"
+
+	// Annotation marking the KwiteClass used by kwites that name no class.
+	DefaultClassAnnotation = "kwiteclass.kwite.site/is-default-class"
+)
+
+// KwiteClassScaling defines the default replica bounds and autoscaling target
+type KwiteClassScaling struct {
+	// +kubebuilder:validation:Minimum=1
+
+	// The default minimum number of page handler replicas
+	// +optional
+	MinReplicas int32 `json:"minReplicas,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+
+	// The default maximum number of page handler replicas
+	// +optional
+	MaxReplicas int32 `json:"maxReplicas,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+
+	// The default HorizontalPodAutoscaler CPU target utilization per pod
+	// +optional
+	TargetCPU int32 `json:"targetCPU,omitempty"`
+}
+
+// KwiteClassSpec defines the defaults applied to kwites of the class. Each
+// is used only when the kwite leaves the corresponding field unset.
+type KwiteClassSpec struct {
+	// The default container image for the http(s) server
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// The default image pull secrets for container pulls
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// The default security context for kwite instance Pods
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+
+	// The default compute resource requests and limits for each kwite
+	// instance, applied per resource
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// The default replica bounds and autoscaling target
+	// +optional
+	Scaling KwiteClassScaling `json:"scaling,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=kwc
+// +kubebuilder:printcolumn:name="Image",type="string",JSONPath=".spec.image"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// KwiteClass is the Schema for the kwiteclasses API
+type KwiteClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec KwiteClassSpec `json:"spec,omitempty"`
+}
+
+// IsDefault reports whether the class is annotated as the default class
+func (c *KwiteClass) IsDefault() bool {
+	return c.Annotations[DefaultClassAnnotation] == "true"
+}
+
+// +kubebuilder:object:root=true
+
+// KwiteClassList contains a list of KwiteClass
+type KwiteClassList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KwiteClass `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KwiteClass{}, &KwiteClassList{})
+}
@@ -0,0 +1,77 @@
+/*
+kwitetemplatelibrary_types.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package v1beta2
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	LibraryKind = "KwiteTemplateLibrary"
+)
+
+// KwiteTemplateLibrarySpec defines the desired state of KwiteTemplateLibrary
+type KwiteTemplateLibrarySpec struct {
+	// Named templates (e.g., header, footer and helpers) made available to
+	// kwites referencing the library, as if each was wrapped in a
+	// {{ define "name" }} block. Kwites use them via {{ template "name" }}.
+	Templates map[string]string `json:"templates"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=ktl
+
+// KwiteTemplateLibrary is the Schema for the kwitetemplatelibraries API
+type KwiteTemplateLibrary struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec KwiteTemplateLibrarySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KwiteTemplateLibraryList contains a list of KwiteTemplateLibrary
+type KwiteTemplateLibraryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KwiteTemplateLibrary `json:"items"`
+}
+
+// LibraryDefinitions returns the library templates as {{ define }} blocks,
+// ready to be appended to a kwite template. A later library takes precedence
+// over an earlier one defining the same name.
+func LibraryDefinitions(libs []KwiteTemplateLibrary) string {
+	merged := make(map[string]string)
+	for i := range libs {
+		for name, text := range libs[i].Spec.Templates {
+			merged[name] = text
+		}
+	}
+
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "{{ define %q }}%s{{ end }}", name, merged[name])
+	}
+	return b.String()
+}
+
+func init() {
+	SchemeBuilder.Register(&KwiteTemplateLibrary{}, &KwiteTemplateLibraryList{})
+}
@@ -0,0 +1,80 @@
+/*
+kwitetemplatelibrary_webhook.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package v1beta2
+
+import (
+	"text/template"
+
+	"github.com/tdhite/kwite/pkg/funcs"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var kwitetemplatelibrarylog = logf.Log.WithName("kwitetemplatelibrary-resource")
+
+func (r *KwiteTemplateLibrary) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-web-kwite-site-v1beta2-kwitetemplatelibrary,mutating=false,failurePolicy=fail,groups=web.kwite.site,resources=kwitetemplatelibraries,versions=v1beta2,name=vkwitetemplatelibrary.v1beta2.kwite.site
+
+var _ webhook.Validator = &KwiteTemplateLibrary{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *KwiteTemplateLibrary) ValidateCreate() error {
+	kwitetemplatelibrarylog.Info("validate create", "name", r.Name)
+
+	return r.validateKwiteTemplateLibrary()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *KwiteTemplateLibrary) ValidateUpdate(old runtime.Object) error {
+	kwitetemplatelibrarylog.Info("validate update", "name", r.Name)
+
+	return r.validateKwiteTemplateLibrary()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *KwiteTemplateLibrary) ValidateDelete() error {
+	kwitetemplatelibrarylog.Info("validate delete", "name", r.Name)
+
+	return nil
+}
+
+// Validate that every library template parses as the definition kwites are
+// given, so a broken library is refused before any kwite picks it up. Each
+// entry is parsed alone to point the error at it.
+func (r *KwiteTemplateLibrary) validateKwiteTemplateLibrary() error {
+	var allErrs field.ErrorList
+
+	fldPath := field.NewPath("spec").Child("templates")
+	for name, text := range r.Spec.Templates {
+		lib := KwiteTemplateLibrary{Spec: KwiteTemplateLibrarySpec{Templates: map[string]string{name: text}}}
+		t := template.New(name).Funcs(funcs.TextTemplateFuncs())
+		if _, err := t.Parse(LibraryDefinitions([]KwiteTemplateLibrary{lib})); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(name), r.Name, err.Error()))
+		}
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: "web.kwite.site", Kind: LibraryKind},
+		r.Name, allErrs)
+}
@@ -0,0 +1,92 @@
+/*
+apply.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// The field manager the operator applies child resources as
+	fieldManager = "kwite-operator"
+)
+
+// Apply the desired state of a child resource with server-side apply, so the
+// operator owns only the fields it sets and leaves the rest to others. Fields
+// another manager has since taken are recorded as conflicts, then taken back
+// so the kwite remains the source of truth.
+func (r *reconcileContext) applyChild(ctx context.Context, obj runtime.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	name := accessor.GetName()
+
+	// note the version applied over, to tell creates and updates apart
+	var previousVersion string
+	current := obj.DeepCopyObject()
+	if err := r.Get(ctx, types.NamespacedName{Namespace: accessor.GetNamespace(), Name: name}, current); err == nil {
+		if m, err := meta.Accessor(current); err == nil {
+			previousVersion = m.GetResourceVersion()
+		}
+	} else if !apierrs.IsNotFound(err) {
+		return err
+	}
+
+	err = r.Patch(ctx, obj, client.Apply, client.FieldOwner(fieldManager))
+	if apierrs.IsConflict(err) {
+		r.reconcileLog.Info("Field ownership conflict applying " + gvk.Kind + ": " + err.Error())
+		msgs := conflictMessages(gvk.Kind, err)
+		r.conflicts = append(r.conflicts, msgs...)
+		r.Recorder.Eventf(r.kwite, corev1.EventTypeWarning, eventReasonFieldOwnershipConflict,
+			"Took back fields of %s %s changed by another manager: %s", gvk.Kind, name, strings.Join(msgs, "; "))
+		err = r.Patch(ctx, obj, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case previousVersion == "":
+		r.Recorder.Eventf(r.kwite, corev1.EventTypeNormal, eventReasonChildCreated, "Created %s %s", gvk.Kind, name)
+	case previousVersion != accessor.GetResourceVersion():
+		r.Recorder.Eventf(r.kwite, corev1.EventTypeNormal, eventReasonChildUpdated, "Updated %s %s", gvk.Kind, name)
+	}
+	return nil
+}
+
+// Return a message per conflicting field of an apply conflict error.
+func conflictMessages(kind string, err error) []string {
+	status, ok := err.(apierrs.APIStatus)
+	if !ok || status.Status().Details == nil || len(status.Status().Details.Causes) == 0 {
+		return []string{fmt.Sprintf("%s: %v", kind, err)}
+	}
+
+	var msgs []string
+	for _, cause := range status.Status().Details.Causes {
+		msgs = append(msgs, fmt.Sprintf("%s %s: %s", kind, cause.Field, cause.Message))
+	}
+	return msgs
+}
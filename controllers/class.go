@@ -0,0 +1,80 @@
+/*
+class.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	"context"
+
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// Index of the KwiteClass a kwite names
+	kwiteClassKey = ".spec.kwiteClassName"
+)
+
+// Return the class the kwite names, for indexing.
+func getKwiteClassName(rawObj runtime.Object) []string {
+	kwite := rawObj.(*webv1beta2.Kwite)
+	if kwite.Spec.KwiteClassName == "" {
+		return nil
+	}
+	return []string{kwite.Spec.KwiteClassName}
+}
+
+// Map a KwiteClass to reconcile requests for the kwites of the class. Every
+// kwite naming no class belongs to the default class.
+func (r *KwiteReconciler) kwitesForClass(obj handler.MapObject) []reconcile.Request {
+	ctx := context.Background()
+
+	var kwites webv1beta2.KwiteList
+	if err := r.List(ctx, &kwites, client.MatchingFields{kwiteClassKey: obj.Meta.GetName()}); err != nil {
+		r.Log.Error(err, "Unable to list kwites of class "+obj.Meta.GetName())
+		return nil
+	}
+
+	items := kwites.Items
+	if obj.Meta.GetAnnotations()[webv1beta2.DefaultClassAnnotation] == "true" {
+		var all webv1beta2.KwiteList
+		if err := r.List(ctx, &all); err != nil {
+			r.Log.Error(err, "Unable to list kwites of the default class")
+			return nil
+		}
+		for _, kwite := range all.Items {
+			if kwite.Spec.KwiteClassName == "" {
+				items = append(items, kwite)
+			}
+		}
+	}
+
+	reqs := make([]reconcile.Request, 0, len(items))
+	for _, kwite := range items {
+		reqs = append(reqs, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: kwite.Namespace, Name: kwite.Name},
+		})
+	}
+	return reqs
+}
+
+// Fill the fields the kwite leaves unset from its class and the built-in
+// defaults. The kwite is changed in memory only, so the children follow the
+// class as it changes. Without the class the built-in defaults still apply.
+func (r *reconcileContext) applyKwiteClass(ctx context.Context) {
+	class, err := r.kwite.GetKwiteClass(ctx, r)
+	if err != nil {
+		r.reconcileLog.Error(err, "Unable to load kwite class, using built-in defaults")
+		class = nil
+	}
+	r.kwite.ApplyDefaults(class)
+}
@@ -10,25 +10,35 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"sort"
 
-	webv1beta1 "github.com/tdhite/kwite-operator/api/v1beta1"
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
 const (
-	cmOwnerKey = ".metadata.controller"
+	cmOwnerKey   = ".metadata.controller"
+	cmRewriteKey = "rewrite"
+
+	// Pod template annotation holding the hash of the generated ConfigMap
+	// content, so a change to the templates or files rolls the pods.
+	configHashAnnotation = "web.kwite.site/config-hash"
 )
 
-// Return all Kwite owned ConfigMaps.
-func (r *KwiteReconciler) getAllConfigMaps(ctx context.Context, req ctrl.Request) (corev1.ConfigMapList, error) {
+// Return all Kwite owned ConfigMaps, in every namespace.
+func (r *reconcileContext) getAllConfigMaps(ctx context.Context) (corev1.ConfigMapList, error) {
 	var cmList corev1.ConfigMapList
-	if err := r.List(ctx, &cmList, client.InNamespace(req.Namespace), client.MatchingFields{cmOwnerKey: webv1beta1.ControllerName}); err != nil {
+	if err := r.List(ctx, &cmList, client.MatchingFields{cmOwnerKey: webv1beta2.ControllerName}); err != nil {
 		r.reconcileLog.Error(err, "Unable to obtain child ConfigMap list.")
 		return cmList, err
 	}
@@ -36,7 +46,7 @@ func (r *KwiteReconciler) getAllConfigMaps(ctx context.Context, req ctrl.Request
 }
 
 // Update the Kwite URL Map to storage.
-func (r *KwiteReconciler) updateUrlMap(ctx context.Context, cm *corev1.ConfigMap, m map[string]string) error {
+func (r *reconcileContext) updateUrlMap(ctx context.Context, cm *corev1.ConfigMap, m map[string]string) error {
 	b, err := json.Marshal(m)
 	if err != nil {
 		r.reconcileLog.Error(err, "Failed to convert rewrite map to JSON.")
@@ -44,8 +54,7 @@ func (r *KwiteReconciler) updateUrlMap(ctx context.Context, cm *corev1.ConfigMap
 	}
 
 	r.reconcileLog.Info("Updating rewrite rules for ConfigMap " + cm.ObjectMeta.Name + "/" + cm.ObjectMeta.Namespace)
-	cm.Data["rewrite"] = string(b)
-	if err := r.Update(ctx, cm); err != nil {
+	if err := setRewrite(ctx, r, cm, string(b)); err != nil {
 		r.reconcileLog.Error(err, "Failed to update reformed ConfigMap.")
 		return err
 	}
@@ -54,7 +63,7 @@ func (r *KwiteReconciler) updateUrlMap(ctx context.Context, cm *corev1.ConfigMap
 }
 
 // Return a map from the provided JSON string.
-func (r *KwiteReconciler) urlMapFromJson(s string) (map[string]string, error) {
+func (r *reconcileContext) urlMapFromJson(s string) (map[string]string, error) {
 	var m map[string]string
 	if s == "" {
 		return make(map[string]string), nil
@@ -69,61 +78,115 @@ func (r *KwiteReconciler) urlMapFromJson(s string) (map[string]string, error) {
 	}
 }
 
-// Fixup all Kwite owned ConfigMaps with the appropriate kwite
-// scheme Url mapping .
-func (r *KwiteReconciler) reformKwiteUrls(ctx context.Context, req ctrl.Request) bool {
-	doUpdate := false
-	cmList, err := r.getAllConfigMaps(ctx, req)
-	if err == nil {
-		for _, cm := range cmList.Items {
-			rewriteMap, err := r.urlMapFromJson(cm.Data["rewrite"])
-			if err != nil {
-				r.reconcileLog.Info("JSON rewrite info failed marshall to string for " + req.NamespacedName.String())
-				continue
-			}
-			key := r.getServiceHostName(req)
-			if rewriteMap[key] != r.kwite.Status.Address {
-				r.reconcileLog.Info("Updating URL map entry for " + key + " to " + r.kwite.Status.Address)
-				rewriteMap[key] = r.kwite.Status.Address
-				if err := r.updateUrlMap(ctx, &cm, rewriteMap); err != nil {
-					doUpdate = true
-				}
-			}
+// Delete the url mapping for the kwite from the ConfigMaps of the other
+// kwites, returning the number of ConfigMaps updated. The registry drops
+// the entry as well, this just does not wait for it.
+func (r *reconcileContext) removeKwiteUrl(ctx context.Context, req ctrl.Request) (int, error) {
+	cmList, err := r.getAllConfigMaps(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var kwites webv1beta2.KwiteList
+	if err := r.List(ctx, &kwites); err != nil {
+		r.reconcileLog.Error(err, "Unable to list kwites.")
+		return 0, err
+	}
+	suspended := getSuspendedKwites(kwites.Items, r.SuspendSelector)
+
+	key := r.getServiceHostName(req)
+	var errs []error
+	removed := 0
+	for _, cm := range cmList.Items {
+		if cm.Name == req.Name && cm.Namespace == req.Namespace {
+			// the kwite's own ConfigMap goes along with it
+			continue
+		}
+		if isSuspendedConfigMap(&cm, suspended) {
+			// left to the registry once the kwite resumes
+			continue
 		}
+		rewriteMap, err := r.urlMapFromJson(cm.Data[cmRewriteKey])
+		if err != nil {
+			r.reconcileLog.Info("JSON rewrite info failed marshall to string for " + req.NamespacedName.String())
+			continue
+		}
+		if _, ok := rewriteMap[key]; !ok {
+			continue
+		}
+		r.reconcileLog.Info("Deleting URL map entry for " + key + " from ConfigMap " + cm.ObjectMeta.Name)
+		delete(rewriteMap, key)
+		if err := r.updateUrlMap(ctx, &cm, rewriteMap); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
 	}
-	return doUpdate
+	return removed, utilerrors.NewAggregate(errs)
 }
 
-// Delete the url mapping for the quite from the ConfigMap.
-func (r *KwiteReconciler) removeKwiteUrl(ctx context.Context, req ctrl.Request) bool {
-	doUpdate := false
-	cmList, err := r.getAllConfigMaps(ctx, req)
-	if err == nil {
-		for _, cm := range cmList.Items {
-			r.reconcileLog.Info("Deleting kwite url map from ConfigMap " + cm.ObjectMeta.Name)
-			rewriteMap, err := r.urlMapFromJson(cm.Data["rewrite"])
-			if err != nil {
-				r.reconcileLog.Info("JSON rewrite info failed marshall to string for " + req.NamespacedName.String())
-				continue
-			}
-			key := r.getServiceHostName(req)
-			r.reconcileLog.Info("Updating URL map entry for " + key)
-			delete(rewriteMap, key)
-			if err := r.updateUrlMap(ctx, &cm, rewriteMap); err != nil {
-				doUpdate = true
-			}
-		}
+// Build the ConfigMap data for the kwite routes and files, with the
+// templates loaded from other objects and libraries.
+func (r *reconcileContext) getConfigMapData(ctx context.Context) (map[string]string, map[string][]byte, error) {
+	resolved, err := r.getResolvedKwite(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
-	return doUpdate
+
+	libs, err := r.kwite.GetLibraries(ctx, r)
+	if err != nil {
+		r.reconcileLog.Error(err, "Failed to load template libraries.")
+		return nil, nil, err
+	}
+
+	d, bd := resolved.ConfigData(libs)
+	return d, bd, nil
+}
+
+// Return a hash of the generated ConfigMap content. The rewrite map is left
+// out, as it is maintained apart from the kwite.
+func getConfigHash(d map[string]string, bd map[string][]byte) string {
+	h := sha256.New()
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%x\n", k, d[k])
+	}
+
+	keys = keys[:0]
+	for k := range bd {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "binary:%s=%x\n", k, bd[k])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Return an error if the ConfigMap, along with the rewrite map the registry
+// keeps in it, holds more than the API server accepts.
+func checkConfigMapSize(cm *corev1.ConfigMap, rewrite string) error {
+	size := webv1beta2.DataSize(cm.Data, cm.BinaryData)
+	if rewrite != "" {
+		size += len(cmRewriteKey) + len(rewrite)
+	}
+	if size > webv1beta2.MaxConfigMapSize {
+		return fmt.Errorf("ConfigMap %s holds %d bytes, more than the limit of %d bytes",
+			cm.Name, size, webv1beta2.MaxConfigMapSize)
+	}
+	return nil
 }
 
 // getConfigMap creates a configmap for kwite deployments
-func (r *KwiteReconciler) getConfigMap(req ctrl.Request) (*corev1.ConfigMap, error) {
-	d := map[string]string{
-		"url":      r.kwite.Spec.Url,
-		"template": r.kwite.Spec.Template,
-		"ready":    r.kwite.Spec.Ready,
-		"alive":    r.kwite.Spec.Alive,
+func (r *reconcileContext) getConfigMap(ctx context.Context, req ctrl.Request) (*corev1.ConfigMap, error) {
+	d, bd, err := r.getConfigMapData(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	cm := &corev1.ConfigMap{
@@ -136,7 +199,18 @@ func (r *KwiteReconciler) getConfigMap(req ctrl.Request) (*corev1.ConfigMap, err
 			Name:      req.Name,
 			Namespace: req.Namespace,
 		},
-		Data: d,
+		Data:       d,
+		BinaryData: bd,
+	}
+
+	// leave room for the rewrite map the registry copied in
+	current := &corev1.ConfigMap{}
+	if err := r.Get(ctx, req.NamespacedName, current); err != nil && !apierrs.IsNotFound(err) {
+		return nil, err
+	}
+	if err := checkConfigMapSize(cm, current.Data[cmRewriteKey]); err != nil {
+		r.reconcileLog.Error(err, "ConfigMap too large")
+		return nil, err
 	}
 
 	if err := ctrl.SetControllerReference(r.kwite, cm, r.Scheme); err != nil {
@@ -147,60 +221,20 @@ func (r *KwiteReconciler) getConfigMap(req ctrl.Request) (*corev1.ConfigMap, err
 	return cm, nil
 }
 
-// Reconcile the ConfigMap's observed cluster state relative to desired state.
-func (r *KwiteReconciler) reconcileConfigMap(ctx context.Context, req ctrl.Request) error {
-	cm := &corev1.ConfigMap{}
-
-	if err := r.Get(ctx, req.NamespacedName, cm); err != nil {
-		if apierrs.IsNotFound(err) {
-			// Need to create a new ConfigMap for this kwite
-			cm, err = r.getConfigMap(req)
-			if err != nil {
-				r.reconcileLog.Error(err, "Failed to configure ConfigMap")
-				return err
-			}
-			if err = r.Create(ctx, cm); err != nil {
-				r.reconcileLog.Error(err, "unable to create ConfigMap")
-				return err
-			}
-		} else {
-			r.reconcileLog.Error(err, "unable to retrieve ConfigMap")
-			return err
-		}
+// Reconcile the ConfigMap's observed cluster state relative to desired state,
+// returning the hash of the content applied. The rewrite map is not applied,
+// the registry maintains it.
+func (r *reconcileContext) reconcileConfigMap(ctx context.Context, req ctrl.Request) (string, error) {
+	cm, err := r.getConfigMap(ctx, req)
+	if err != nil {
+		r.reconcileLog.Error(err, "Failed to configure ConfigMap")
+		return "", err
 	}
 
-	// Check current state against the loaded cm.
-	// However, if deleting, just leave it alone.
-	doUpdate := false
-	if cm.ObjectMeta.DeletionTimestamp.IsZero() {
-		if r.kwite.Spec.Url != cm.Data["url"] {
-			cm.Data["url"] = r.kwite.Spec.Url
-			doUpdate = true
-		}
-		if r.kwite.Spec.Template != cm.Data["template"] {
-			cm.Data["template"] = r.kwite.Spec.Template
-			doUpdate = true
-		}
-		if r.kwite.Spec.Ready != cm.Data["ready"] {
-			cm.Data["ready"] = r.kwite.Spec.Ready
-			doUpdate = true
-		}
-		if r.kwite.Spec.Alive != cm.Data["alive"] {
-			cm.Data["alive"] = r.kwite.Spec.Alive
-			doUpdate = true
-		}
-
-		r.reformKwiteUrls(ctx, req)
-
-		if doUpdate {
-			r.reconcileLog.Info("Updating ConfigMap " + cm.GetName())
-			err := r.Update(ctx, cm)
-			if err != nil {
-				r.reconcileLog.Error(err, "Failed to update ConfigMap.")
-				return err
-			}
-		}
+	if err := r.applyChild(ctx, cm); err != nil {
+		r.reconcileLog.Error(err, "Failed to apply ConfigMap.")
+		return "", err
 	}
 
-	return nil
+	return getConfigHash(cm.Data, cm.BinaryData), nil
 }
@@ -10,26 +10,41 @@ package controllers
 
 import (
 	"context"
-	"path"
+	"fmt"
 
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 const (
-	kwiteReady string = "kwiteready"
-	kwiteAlive string = "kwitealive"
+	kwiteReady   string = "kwiteready"
+	kwiteAlive   string = "kwitealive"
+	kwiteConfigs string = "configs"
 )
 
-// Create, initialize and return a new Deployent.
-func (r *KwiteReconciler) getDeployment(req ctrl.Request) (*appsv1.Deployment, error) {
-	replicas := int32(r.kwite.Spec.MinReplicas)
+// Return the replica count of the Deployment when autoscaling is off.
+func (r *reconcileContext) getDesiredReplicas() int32 {
+	return r.kwite.Spec.Scaling.DesiredReplicas()
+}
+
+// Return the number of replicas the kwite needs ready: the minimum when
+// autoscaling, otherwise the replicas it is scaled to, which may be zero.
+func (r *reconcileContext) getMinimumReplicas() int32 {
+	if r.kwite.Spec.Scaling.AutoscalingEnabled() {
+		return r.kwite.Spec.Scaling.MinReplicas
+	}
+	return r.getDesiredReplicas()
+}
+
+// Create, initialize and return a new Deployent, its pod template stamped
+// with the hash of the ConfigMap content applied.
+func (r *reconcileContext) getDeployment(ctx context.Context, req ctrl.Request, configHash string) (*appsv1.Deployment, error) {
+	startup, liveness, readiness := r.getProbes()
 	lbls := getLabelSelector(req)
 	matchLabels := metav1.LabelSelector{MatchLabels: getLabelSelector(req)}
 
@@ -38,6 +53,18 @@ func (r *KwiteReconciler) getDeployment(req ctrl.Request) (*appsv1.Deployment, e
 		ips = r.kwite.Spec.ImagePullSecrets
 	}
 
+	envHash, err := r.getEnvHash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	annotations := make(map[string]string)
+	if configHash != "" {
+		annotations[configHashAnnotation] = configHash
+	}
+	if envHash != "" {
+		annotations[envHashAnnotation] = envHash
+	}
+
 	d := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      req.Name,
@@ -45,11 +72,11 @@ func (r *KwiteReconciler) getDeployment(req ctrl.Request) (*appsv1.Deployment, e
 			Labels:    lbls,
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: &replicas,
 			Selector: &matchLabels,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: lbls,
+					Labels:      lbls,
+					Annotations: annotations,
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
@@ -62,66 +89,23 @@ func (r *KwiteReconciler) getDeployment(req ctrl.Request) (*appsv1.Deployment, e
 									ContainerPort: kwitePort,
 								},
 							},
-							Resources: corev1.ResourceRequirements{
-								Requests: corev1.ResourceList{
-									corev1.ResourceCPU:    resource.MustParse(r.kwite.Spec.CPU),
-									corev1.ResourceMemory: resource.MustParse(r.kwite.Spec.Memory),
-								},
-							},
+							Resources:       r.getResources(),
+							Env:             r.kwite.Spec.Env,
+							EnvFrom:         r.kwite.Spec.EnvFrom,
 							SecurityContext: r.kwite.Spec.SecurityContext,
 							VolumeMounts: []corev1.VolumeMount{
 								{
-									Name:      "configs",
-									MountPath: "/configs",
-								},
-							},
-							StartupProbe: &corev1.Probe{
-								Handler: corev1.Handler{
-									HTTPGet: &corev1.HTTPGetAction{
-										Path: path.Join(r.kwite.Spec.Url, kwiteAlive),
-										Port: intstr.IntOrString{
-											IntVal: kwitePort,
-										},
-									},
-								},
-								FailureThreshold: 5,
-								PeriodSeconds:    1,
-							},
-							LivenessProbe: &corev1.Probe{
-								Handler: corev1.Handler{
-									HTTPGet: &corev1.HTTPGetAction{
-										Path: path.Join(r.kwite.Spec.Url, kwiteAlive),
-										Port: intstr.IntOrString{
-											IntVal: kwitePort,
-										},
-									},
-								},
-								PeriodSeconds: 3,
-							},
-							ReadinessProbe: &corev1.Probe{
-								Handler: corev1.Handler{
-									HTTPGet: &corev1.HTTPGetAction{
-										Path: path.Join(r.kwite.Spec.Url, kwiteReady),
-										Port: intstr.IntOrString{
-											IntVal: kwitePort,
-										},
-									},
+									Name:      kwiteConfigs,
+									MountPath: "/" + kwiteConfigs,
 								},
-								PeriodSeconds: 3,
 							},
+							StartupProbe:   startup,
+							LivenessProbe:  liveness,
+							ReadinessProbe: readiness,
 						},
 					},
 					Volumes: []corev1.Volume{
-						{
-							Name: "configs",
-							VolumeSource: corev1.VolumeSource{
-								ConfigMap: &corev1.ConfigMapVolumeSource{
-									LocalObjectReference: corev1.LocalObjectReference{
-										Name: req.Name,
-									},
-								},
-							},
-						},
+						r.getConfigsVolume(req),
 					},
 					ImagePullSecrets: ips,
 				},
@@ -129,6 +113,19 @@ func (r *KwiteReconciler) getDeployment(req ctrl.Request) (*appsv1.Deployment, e
 		},
 	}
 
+	r.setPlacement(req, &d.Spec.Template.Spec)
+	r.setRollout(&d.Spec)
+
+	if err := r.patchPodTemplate(req, &d.Spec.Template); err != nil {
+		return nil, err
+	}
+
+	// replicas get managed by HPA, unless autoscaling is off
+	if !r.kwite.Spec.Scaling.AutoscalingEnabled() {
+		replicas := r.getDesiredReplicas()
+		d.Spec.Replicas = &replicas
+	}
+
 	if err := ctrl.SetControllerReference(r.kwite, d, r.Scheme); err != nil {
 		r.reconcileLog.Error(err, "Could not set kwite as owner of Deployment: "+req.Name)
 		return nil, err
@@ -136,70 +133,174 @@ func (r *KwiteReconciler) getDeployment(req ctrl.Request) (*appsv1.Deployment, e
 	return d, nil
 }
 
-func (r *KwiteReconciler) updateDeploymentStatus(ctx context.Context, req ctrl.Request) bool {
+func (r *reconcileContext) updateDeploymentStatus(ctx context.Context, req ctrl.Request) {
 	dep := &appsv1.Deployment{}
-	doUpdate := false
+	r.kwite.Status.Selector = metav1.FormatLabelSelector(&metav1.LabelSelector{MatchLabels: getLabelSelector(req)})
 
 	if err := r.Get(ctx, req.NamespacedName, dep); err != nil {
 		// no matter the error, no status update
 		if apierrs.IsNotFound(err) {
 			r.reconcileLog.Info("Deployment does not exist for status update in namespace: " + req.NamespacedName.String())
+			msg := "Deployment " + req.Name + " does not exist"
+			r.setCondition(webv1beta2.KwiteAvailable, corev1.ConditionFalse, reasonDeploymentNotFound, msg)
+			r.setCondition(webv1beta2.KwiteProgressing, corev1.ConditionUnknown, reasonDeploymentNotFound, msg)
+			r.setCondition(webv1beta2.KwiteDegraded, corev1.ConditionFalse, reasonAsExpected, "")
 		} else {
 			r.reconcileLog.Error(err, "Failed Deployment retrieve for status update in namespace: "+req.NamespacedName.String())
 		}
+		return
+	}
+
+	r.kwite.Status.ReadyReplicas = dep.Status.ReadyReplicas
+	r.kwite.Status.UpdatedReplicas = dep.Status.UpdatedReplicas
+	r.kwite.Status.AvailableReplicas = dep.Status.AvailableReplicas
+	minReplicas := r.getMinimumReplicas()
+	r.kwite.Status.Ready = dep.Status.ReadyReplicas >= minReplicas
+
+	// Available
+	if dep.Status.ReadyReplicas >= minReplicas && (dep.Status.AvailableReplicas > 0 || minReplicas == 0) {
+		r.setCondition(webv1beta2.KwiteAvailable, corev1.ConditionTrue, reasonMinimumReplicasAvailable,
+			fmt.Sprintf("%d of minimum %d replicas ready", dep.Status.ReadyReplicas, minReplicas))
 	} else {
-		r.kwite.Status.ReadyReplicas = int(dep.Status.ReadyReplicas)
-		r.kwite.Status.Ready = dep.Status.ReadyReplicas == int32(r.kwite.Spec.MinReplicas)
-		doUpdate = true
+		r.setCondition(webv1beta2.KwiteAvailable, corev1.ConditionFalse, reasonMinimumReplicasUnavailable,
+			fmt.Sprintf("%d of minimum %d replicas ready", dep.Status.ReadyReplicas, minReplicas))
 	}
-	return doUpdate
-}
 
-// Reconcile the Deployment cluster state.
-func (r *KwiteReconciler) reconcileDeployment(ctx context.Context, req ctrl.Request) error {
-	dep := &appsv1.Deployment{}
+	// Progressing
+	progressing := getDeploymentCondition(dep, appsv1.DeploymentProgressing)
+	replicas := int32(1)
+	if dep.Spec.Replicas != nil {
+		replicas = *dep.Spec.Replicas
+	}
+	if progressing != nil && progressing.Status == corev1.ConditionFalse && progressing.Reason == reasonProgressDeadlineExceeded {
+		r.setCondition(webv1beta2.KwiteProgressing, corev1.ConditionFalse, reasonProgressDeadlineExceeded, progressing.Message)
+	} else if dep.Status.ObservedGeneration < dep.Generation || dep.Status.UpdatedReplicas < replicas ||
+		dep.Status.Replicas > dep.Status.UpdatedReplicas || dep.Status.AvailableReplicas < dep.Status.UpdatedReplicas {
+		r.setCondition(webv1beta2.KwiteProgressing, corev1.ConditionTrue, reasonRolloutInProgress,
+			fmt.Sprintf("%d of %d replicas updated, %d available", dep.Status.UpdatedReplicas, replicas, dep.Status.AvailableReplicas))
+	} else {
+		r.setCondition(webv1beta2.KwiteProgressing, corev1.ConditionFalse, reasonRolloutComplete, "Deployment rollout complete")
+	}
 
-	if err := r.Get(ctx, req.NamespacedName, dep); err != nil {
-		if apierrs.IsNotFound(err) {
-			// No deployment, create it
-			dep, err = r.getDeployment(req)
-			if err != nil {
-				r.reconcileLog.Error(err, "failed to create deployment resource")
-				return err
-			}
-			if err = r.Create(ctx, dep); err != nil {
-				r.reconcileLog.Error(err, "failed to create Deployment on the cluster")
-				return err
-			}
-		} else {
-			r.reconcileLog.Error(err, "unable to retrieve Deployment in namespace "+req.Namespace)
-			return err
+	// Degraded
+	if failure := getDeploymentCondition(dep, appsv1.DeploymentReplicaFailure); failure != nil && failure.Status == corev1.ConditionTrue {
+		r.setCondition(webv1beta2.KwiteDegraded, corev1.ConditionTrue, reasonReplicaFailure, failure.Message)
+	} else if progressing != nil && progressing.Status == corev1.ConditionFalse && progressing.Reason == reasonProgressDeadlineExceeded {
+		r.setCondition(webv1beta2.KwiteDegraded, corev1.ConditionTrue, reasonProgressDeadlineExceeded, progressing.Message)
+	} else {
+		r.setCondition(webv1beta2.KwiteDegraded, corev1.ConditionFalse, reasonAsExpected, "")
+	}
+}
+
+// Return the kwite container resources. A limit without a request also
+// requests the limit, as the API server would default it.
+func (r *reconcileContext) getResources() corev1.ResourceRequirements {
+	res := *r.kwite.Spec.Resources.DeepCopy()
+	for name, limit := range res.Limits {
+		if _, ok := res.Requests[name]; ok {
+			continue
 		}
+		if res.Requests == nil {
+			res.Requests = make(corev1.ResourceList)
+		}
+		res.Requests[name] = limit.DeepCopy()
 	}
+	return res
+}
 
-	// Check current state against the loaded deployment and update as needed.
-	// However, if deleting, just leave it alone.
-	doUpdate := false
-	if dep.ObjectMeta.DeletionTimestamp.IsZero() {
-		// note: replicas get managed by HPA
-		if r.kwite.Spec.Image != dep.Spec.Template.Spec.Containers[0].Image {
-			dep.Spec.Template.Spec.Containers[0].Image = r.kwite.Spec.Image
-			doUpdate = true
+// Return the volume mounted at /configs. It holds the kwite ConfigMap and,
+// with spec.filesFrom, projects the listed ConfigMaps and Secrets alongside
+// it. The kwite ConfigMap comes last so its keys win any conflict.
+func (r *reconcileContext) getConfigsVolume(req ctrl.Request) corev1.Volume {
+	mode := corev1.ConfigMapVolumeSourceDefaultMode
+	cmRef := corev1.LocalObjectReference{Name: req.Name}
+
+	if len(r.kwite.Spec.FilesFrom) == 0 {
+		return corev1.Volume{
+			Name: kwiteConfigs,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: cmRef,
+					DefaultMode:          &mode,
+				},
+			},
 		}
-		iVal := int(dep.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort)
-		if r.kwite.Spec.Port != iVal {
-			dep.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort = int32(r.kwite.Spec.Port)
-			doUpdate = true
+	}
+
+	mode = corev1.ProjectedVolumeSourceDefaultMode
+	sources := make([]corev1.VolumeProjection, 0, len(r.kwite.Spec.FilesFrom)+1)
+	for _, from := range r.kwite.Spec.FilesFrom {
+		if from.ConfigMapRef != nil {
+			sources = append(sources, corev1.VolumeProjection{
+				ConfigMap: &corev1.ConfigMapProjection{
+					LocalObjectReference: *from.ConfigMapRef,
+					Optional:             from.Optional,
+				},
+			})
+		} else if from.SecretRef != nil {
+			sources = append(sources, corev1.VolumeProjection{
+				Secret: &corev1.SecretProjection{
+					LocalObjectReference: *from.SecretRef,
+					Optional:             from.Optional,
+				},
+			})
 		}
-		if doUpdate {
-			r.reconcileLog.Info("Updating deployment " + dep.GetName())
-			err := r.Update(ctx, dep)
-			if err != nil {
-				r.reconcileLog.Error(err, "Failed to update Deployment.")
-				return err
-			}
+	}
+	sources = append(sources, corev1.VolumeProjection{
+		ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: cmRef},
+	})
+
+	return corev1.Volume{
+		Name: kwiteConfigs,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources:     sources,
+				DefaultMode: &mode,
+			},
+		},
+	}
+}
+
+// Apply the kwite pod template patch, keeping the labels that select the
+// kwite pods.
+func (r *reconcileContext) patchPodTemplate(req ctrl.Request, tmpl *corev1.PodTemplateSpec) error {
+	if err := r.kwite.PatchPodTemplate(tmpl); err != nil {
+		r.reconcileLog.Error(err, "Failed to apply pod template patch")
+		return err
+	}
+	if tmpl.Labels == nil {
+		tmpl.Labels = make(map[string]string)
+	}
+	for k, v := range getLabelSelector(req) {
+		tmpl.Labels[k] = v
+	}
+	return nil
+}
+
+// Return the Deployment condition of the given type, or nil if not present.
+func getDeploymentCondition(dep *appsv1.Deployment, t appsv1.DeploymentConditionType) *appsv1.DeploymentCondition {
+	for i := range dep.Status.Conditions {
+		if dep.Status.Conditions[i].Type == t {
+			return &dep.Status.Conditions[i]
 		}
 	}
+	return nil
+}
+
+// Reconcile the Deployment cluster state, rolling the pods when the hash of
+// the ConfigMap content changes.
+func (r *reconcileContext) reconcileDeployment(ctx context.Context, req ctrl.Request, configHash string) error {
+	dep, err := r.getDeployment(ctx, req, configHash)
+	if err != nil {
+		r.reconcileLog.Error(err, "failed to create deployment resource")
+		return err
+	}
+
+	if err := r.applyChild(ctx, dep); err != nil {
+		r.reconcileLog.Error(err, "Failed to apply Deployment.")
+		return err
+	}
+	r.kwite.Status.ConfigHash = configHash
 
 	return nil
 }
@@ -0,0 +1,132 @@
+/*
+env.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// Pod template annotation holding the hash of the ConfigMaps and Secrets
+	// the kwite environment reads, so a change to them rolls the pods.
+	envHashAnnotation = "web.kwite.site/env-hash"
+)
+
+// Return the ConfigMaps and Secrets the kwite environment reads, as
+// "Kind/name" in spec order.
+func getEnvSources(kwite *webv1beta2.Kwite) []string {
+	var sources []string
+
+	for _, env := range kwite.Spec.Env {
+		if env.ValueFrom == nil {
+			continue
+		}
+		if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil {
+			sources = append(sources, kindConfigMap+"/"+ref.Name)
+		}
+		if ref := env.ValueFrom.SecretKeyRef; ref != nil {
+			sources = append(sources, kindSecret+"/"+ref.Name)
+		}
+	}
+
+	for _, envFrom := range kwite.Spec.EnvFrom {
+		if ref := envFrom.ConfigMapRef; ref != nil {
+			sources = append(sources, kindConfigMap+"/"+ref.Name)
+		}
+		if ref := envFrom.SecretRef; ref != nil {
+			sources = append(sources, kindSecret+"/"+ref.Name)
+		}
+	}
+
+	return sources
+}
+
+// Return a hash of the content of the ConfigMaps and Secrets the kwite
+// environment reads, or "" when it reads none. Missing objects hash as
+// empty; the pods report those themselves.
+func (r *reconcileContext) getEnvHash(ctx context.Context) (string, error) {
+	sources := getEnvSources(r.kwite)
+	if len(sources) == 0 {
+		return "", nil
+	}
+
+	h := sha256.New()
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		if seen[source] {
+			continue
+		}
+		seen[source] = true
+		fmt.Fprintf(h, "%s\n", source)
+
+		data, err := r.getEnvSourceData(ctx, source)
+		if err != nil {
+			r.reconcileLog.Error(err, "Failed to read environment source "+source)
+			return "", err
+		}
+
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(h, "%s=%x\n", k, data[k])
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Return the data of the ConfigMap or Secret named "Kind/name".
+func (r *reconcileContext) getEnvSourceData(ctx context.Context, source string) (map[string][]byte, error) {
+	data := make(map[string][]byte)
+	parts := strings.SplitN(source, "/", 2)
+	key := types.NamespacedName{Namespace: r.kwite.Namespace, Name: parts[1]}
+
+	switch parts[0] {
+	case kindConfigMap:
+		cm := &corev1.ConfigMap{}
+		if err := r.Get(ctx, key, cm); err != nil {
+			if apierrs.IsNotFound(err) {
+				return data, nil
+			}
+			return nil, err
+		}
+		for k, v := range cm.Data {
+			data[k] = []byte(v)
+		}
+		for k, v := range cm.BinaryData {
+			data[k] = v
+		}
+	case kindSecret:
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, key, secret); err != nil {
+			if apierrs.IsNotFound(err) {
+				return data, nil
+			}
+			return nil, err
+		}
+		for k, v := range secret.Data {
+			data[k] = v
+		}
+	}
+
+	return data, nil
+}
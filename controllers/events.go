@@ -0,0 +1,24 @@
+/*
+events.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+// Event reasons, kept stable so they may be alerted on.
+const (
+	eventReasonChildCreated           = "ChildCreated"
+	eventReasonChildUpdated           = "ChildUpdated"
+	eventReasonChildDeleted           = "ChildDeleted"
+	eventReasonReconcileFailed        = "ReconcileFailed"
+	eventReasonFieldOwnershipConflict = "FieldOwnershipConflict"
+	eventReasonTemplateInvalid        = "TemplateInvalid"
+	eventReasonAddressChanged         = "AddressChanged"
+	eventReasonRewriteUpdated         = "RewriteRulesUpdated"
+	eventReasonRewriteUpdateFailed    = "RewriteRulesUpdateFailed"
+	eventReasonRewriteRemoved         = "RewriteRulesRemoved"
+	eventReasonRewriteRemoveFailed    = "RewriteRulesRemoveFailed"
+)
@@ -0,0 +1,85 @@
+/*
+finalizer.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Finalizer holding a deleted kwite until its kwite:// rewrite entries
+	// are removed from the other kwites
+	kwiteFinalizer = "web.kwite.site/rewrite-cleanup"
+)
+
+// Return whether the finalizer is in the list.
+func hasFinalizer(finalizers []string, finalizer string) bool {
+	for _, f := range finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the list without the finalizer.
+func removeFinalizer(finalizers []string, finalizer string) []string {
+	var result []string
+	for _, f := range finalizers {
+		if f != finalizer {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// Add the kwite finalizer to a live kwite or, once the kwite is deleted,
+// remove its rewrite entries and then the finalizer so garbage collection
+// proceeds. Returns whether the kwite is being deleted. The finalizers are
+// patched, leaving the spec alone.
+func (r *reconcileContext) reconcileFinalizer(ctx context.Context, req ctrl.Request) (bool, error) {
+	if r.kwite.DeletionTimestamp.IsZero() {
+		if !hasFinalizer(r.kwite.Finalizers, kwiteFinalizer) {
+			patch := client.MergeFrom(r.kwite.DeepCopy())
+			r.kwite.Finalizers = append(r.kwite.Finalizers, kwiteFinalizer)
+			if err := r.Patch(ctx, r.kwite, patch); err != nil {
+				r.reconcileLog.Error(err, "Failed to add finalizer")
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	if !hasFinalizer(r.kwite.Finalizers, kwiteFinalizer) {
+		return true, nil
+	}
+
+	removed, err := r.removeKwiteUrl(ctx, req)
+	if err != nil {
+		r.reconcileLog.Error(err, "Failed to remove rewrite entries")
+		r.Recorder.Eventf(r.kwite, corev1.EventTypeWarning, eventReasonRewriteRemoveFailed,
+			"Failed to remove the rewrite entry for %s: %v", r.getServiceHostName(req), err)
+		return true, err
+	}
+	r.Recorder.Event(r.kwite, corev1.EventTypeNormal, eventReasonRewriteRemoved,
+		fmt.Sprintf("Removed the rewrite entry for %s from %d ConfigMaps", r.getServiceHostName(req), removed))
+
+	patch := client.MergeFrom(r.kwite.DeepCopy())
+	r.kwite.Finalizers = removeFinalizer(r.kwite.Finalizers, kwiteFinalizer)
+	if err := r.Patch(ctx, r.kwite, patch); err != nil {
+		r.reconcileLog.Error(err, "Failed to remove finalizer")
+		return true, err
+	}
+	return true, nil
+}
@@ -15,14 +15,22 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	asv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 )
 
 // Create, initialize and return a new Horizontal Pod Autoscaler.
-func (r *KwiteReconciler) getHPA(req ctrl.Request) (*asv1.HorizontalPodAutoscaler, error) {
-	minReplicas := int32(r.kwite.Spec.MinReplicas)
-	maxReplicas := int32(r.kwite.Spec.MaxReplicas)
-	targetCPU := int32(r.kwite.Spec.TargetCpu)
+func (r *reconcileContext) getHPA(req ctrl.Request) (*asv1.HorizontalPodAutoscaler, error) {
+	// the API server rejects an autoscaler allowed to scale to zero
+	minReplicas := r.kwite.Spec.Scaling.MinReplicas
+	if minReplicas < 1 {
+		minReplicas = 1
+	}
+	maxReplicas := r.kwite.Spec.Scaling.MaxReplicas
+	if maxReplicas < minReplicas {
+		maxReplicas = minReplicas
+	}
+	targetCPU := r.kwite.Spec.Scaling.TargetCPU
 
 	hpa := &asv1.HorizontalPodAutoscaler{
 		ObjectMeta: metav1.ObjectMeta{
@@ -49,9 +57,13 @@ func (r *KwiteReconciler) getHPA(req ctrl.Request) (*asv1.HorizontalPodAutoscale
 	return hpa, nil
 }
 
-func (r *KwiteReconciler) updateHPAStatus(ctx context.Context, req ctrl.Request) bool {
+func (r *reconcileContext) updateHPAStatus(ctx context.Context, req ctrl.Request) {
 	hpa := &asv1.HorizontalPodAutoscaler{}
-	doUpdate := false
+
+	if !r.kwite.Spec.Scaling.AutoscalingEnabled() {
+		r.kwite.Status.DesiredReplicas = r.getDesiredReplicas()
+		return
+	}
 
 	if err := r.Get(ctx, req.NamespacedName, hpa); err != nil {
 		// no matter the error, no status update
@@ -61,63 +73,55 @@ func (r *KwiteReconciler) updateHPAStatus(ctx context.Context, req ctrl.Request)
 			r.reconcileLog.Error(err, "Failed HPA retrieve for status update in namespace: "+req.NamespacedName.String())
 		}
 	} else {
-		r.kwite.Status.DesiredReplicas = int(hpa.Status.DesiredReplicas)
-		doUpdate = true
+		r.kwite.Status.DesiredReplicas = hpa.Status.DesiredReplicas
 	}
-	return doUpdate
 }
 
-// Reconcile the Horizontal Pod Autoscaler cluster state.
-func (r *KwiteReconciler) reconcileHPA(ctx context.Context, req ctrl.Request) error {
+// Remove the Horizontal Pod Autoscaler so manual scaling takes effect.
+func (r *reconcileContext) deleteHPA(ctx context.Context, req ctrl.Request) error {
 	hpa := &asv1.HorizontalPodAutoscaler{}
 
 	if err := r.Get(ctx, req.NamespacedName, hpa); err != nil {
 		if apierrs.IsNotFound(err) {
-			// Need to create the HPA since it's not there
-			hpa, err = r.getHPA(req)
-			if err != nil {
-				r.reconcileLog.Error(err, "failed to create HPA resource")
-				return err
-			}
-			if err := r.Create(ctx, hpa); err != nil {
-				r.reconcileLog.Error(err, "failed to create HPA on the cluster: ")
-				return err
-			}
-		} else {
-			r.reconcileLog.Error(err, "unable to list HPA items in namespace "+req.Namespace)
-			return err
+			return nil
 		}
+		r.reconcileLog.Error(err, "unable to retrieve HPA in namespace "+req.Namespace)
+		return err
 	}
 
-	// Check current state against the loaded HPA and update as needed.
-	// However, if deleting, just leave it alone.
-	doUpdate := false
-	if hpa.ObjectMeta.DeletionTimestamp.IsZero() {
-		iVal := int(*hpa.Spec.MinReplicas)
-		if r.kwite.Spec.MinReplicas != iVal {
-			r := int32(r.kwite.Spec.MinReplicas)
-			hpa.Spec.MinReplicas = &r
-			doUpdate = true
-		}
-		iVal = int(hpa.Spec.MaxReplicas)
-		if r.kwite.Spec.MaxReplicas != iVal {
-			hpa.Spec.MaxReplicas = int32(r.kwite.Spec.MaxReplicas)
-			doUpdate = true
-		}
-		iVal = int(*hpa.Spec.TargetCPUUtilizationPercentage)
-		if r.kwite.Spec.TargetCpu != iVal {
-			t := int32(r.kwite.Spec.TargetCpu)
-			hpa.Spec.TargetCPUUtilizationPercentage = &t
-			doUpdate = true
-		}
-		if doUpdate {
-			r.reconcileLog.Info("Updating HPA " + hpa.GetName())
-			err := r.Update(ctx, hpa)
-			if err != nil {
-				r.reconcileLog.Error(err, "Failed to update HPA.")
-				return err
-			}
+	if !hpa.ObjectMeta.DeletionTimestamp.IsZero() || !metav1.IsControlledBy(hpa, r.kwite) {
+		return nil
+	}
+
+	r.reconcileLog.Info("Autoscaling disabled, deleting HPA " + hpa.GetName())
+	if err := r.Delete(ctx, hpa); err != nil {
+		if apierrs.IsNotFound(err) {
+			return nil
 		}
+		r.reconcileLog.Error(err, "Failed to delete HPA.")
+		return err
+	}
+	r.Recorder.Eventf(r.kwite, corev1.EventTypeNormal, eventReasonChildDeleted,
+		"Deleted HorizontalPodAutoscaler %s as autoscaling is off", hpa.GetName())
+
+	return nil
+}
+
+// Reconcile the Horizontal Pod Autoscaler cluster state.
+func (r *reconcileContext) reconcileHPA(ctx context.Context, req ctrl.Request) error {
+	if !r.kwite.Spec.Scaling.AutoscalingEnabled() {
+		return r.deleteHPA(ctx, req)
+	}
+
+	hpa, err := r.getHPA(req)
+	if err != nil {
+		r.reconcileLog.Error(err, "failed to create HPA resource")
+		return err
+	}
+
+	if err := r.applyChild(ctx, hpa); err != nil {
+		r.reconcileLog.Error(err, "Failed to apply HPA.")
+		return err
 	}
 
 	return nil
@@ -10,16 +10,27 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	webv1beta1 "github.com/tdhite/kwite-operator/api/v1beta1"
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
 	appsv1 "k8s.io/api/apps/v1"
+	asv1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 )
 
@@ -28,13 +39,47 @@ const (
 	kwitePort int32  = 8080
 )
 
+// The kinds of the child resources a kwite owns.
+var childTypes = []runtime.Object{
+	&corev1.ConfigMap{},
+	&corev1.Service{},
+	&appsv1.Deployment{},
+	&asv1.HorizontalPodAutoscaler{},
+}
+
 // KwiteReconciler reconciles a Kwite object
 type KwiteReconciler struct {
 	client.Client
-	Log          logr.Logger
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// The DNS domain of the cluster, used in Service addresses. Defaults to
+	// cluster.local when empty.
+	ClusterDomain string
+
+	// Whether Service addresses use the cluster IP instead of the DNS name
+	UseClusterIP bool
+
+	// Suspends reconciling the children of kwites whose annotations match,
+	// as if spec.suspend was set. Nil or empty matches no kwite.
+	SuspendSelector labels.Selector
+
+	// The maximum number of kwites reconciled concurrently, one when unset
+	MaxConcurrentReconciles int
+
+	// How long to wait before reconciling a kwite that is not ready again.
+	// Zero waits for the next event instead.
+	NotReadyRequeueInterval time.Duration
+}
+
+// reconcileContext holds the state of reconciling a single kwite, so the
+// requests for different kwites may be reconciled concurrently.
+type reconcileContext struct {
+	*KwiteReconciler
 	reconcileLog logr.Logger
-	Scheme       *runtime.Scheme
-	kwite        *webv1beta1.Kwite
+	kwite        *webv1beta2.Kwite
+	conflicts    []string
 }
 
 func getLabelSelector(req ctrl.Request) map[string]string {
@@ -45,66 +90,121 @@ func getLabelSelector(req ctrl.Request) map[string]string {
 
 // +kubebuilder:rbac:groups=web.kwite.site,resources=kwites,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=web.kwite.site,resources=kwites/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=web.kwite.site,resources=kwitetemplatelibraries,verbs=get;list;watch
+// +kubebuilder:rbac:groups=web.kwite.site,resources=kwiteclasses,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
 
 func (r *KwiteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
-	r.reconcileLog = r.Log.WithValues(kwiteName, req.NamespacedName)
+	log := r.Log.WithValues(kwiteName, req.NamespacedName)
 	res := ctrl.Result{}
 
 	// load the kwite object
-	var kwite webv1beta1.Kwite
+	var kwite webv1beta2.Kwite
 	if err := r.Get(ctx, req.NamespacedName, &kwite); err != nil {
 		if apierrs.IsNotFound(err) {
 			// might have been deleted or is simply not yet created
 			return res, client.IgnoreNotFound(err)
 		} else {
 			// some real error occurred
-			r.reconcileLog.Error(err, "Unable to fetch kwite")
+			log.Error(err, "Unable to fetch kwite")
 			return res, err
 		}
 	}
 
-	// Cache this kwite for reconcilation ease
-	r.kwite = &kwite
+	// Hold this kwite for reconcilation ease
+	rc := &reconcileContext{KwiteReconciler: r, reconcileLog: log, kwite: &kwite}
+
+	// clean up after a deleted kwite, and nothing else
+	if deleting, err := rc.reconcileFinalizer(ctx, req); deleting || err != nil {
+		return res, err
+	}
+	oldStatus := kwite.Status.DeepCopy()
+
+	// fill anything left unset from the kwite class, as the webhooks would
+	rc.applyKwiteClass(ctx)
 
 	// get current status and setup to apply kwite url rewrites where appropriate
-	update := r.updateDeploymentStatus(ctx, req) || r.updateHPAStatus(ctx, req) || r.updateServiceStatus(ctx, req)
+	rc.updateChildStatus(ctx, req)
+	rc.updateTemplateStatus(ctx)
+
+	// reconcile against the various objects, unless suspended; the status
+	// above is reported either way
+	var reconcileErr error
+	if rc.updateSuspendedStatus() {
+		log.Info("Reconciliation suspended, leaving child resources alone")
+	} else {
+		var errs []error
+		// the ConfigMap goes first, so pods rolled for a change to its
+		// content come up with the new content
+		configHash, err := rc.reconcileConfigMap(ctx, req)
+		if err != nil {
+			log.Error(err, "Failed to update ConfigMap for ", req.NamespacedName.String())
+			errs = append(errs, fmt.Errorf("ConfigMap: %w", err))
+			// the pods keep the content last applied
+			configHash = kwite.Status.ConfigHash
+		}
+		if err := rc.reconcileDeployment(ctx, req, configHash); err != nil {
+			log.Error(err, "Failed to update Deployment for ", req.NamespacedName.String())
+			errs = append(errs, fmt.Errorf("Deployment: %w", err))
+		}
+		if err := rc.reconcileService(ctx, req); err != nil {
+			log.Error(err, "Failed to update Service for ", req.NamespacedName.String())
+			errs = append(errs, fmt.Errorf("Service: %w", err))
+		}
+		if err := rc.reconcileHPA(ctx, req); err != nil {
+			log.Error(err, "Failed to update HPA for ", req.NamespacedName.String())
+			errs = append(errs, fmt.Errorf("HorizontalPodAutoscaler: %w", err))
+		}
+		reconcileErr = utilerrors.NewAggregate(errs)
+		rc.updateReconciledStatus(reconcileErr)
+		rc.updateConflictStatus()
+	}
 
-	if update {
+	kwite.Status.ObservedGeneration = kwite.Generation
+	if !apiequality.Semantic.DeepEqual(oldStatus, &kwite.Status) {
 		if err := r.Status().Update(ctx, &kwite); err != nil {
-			r.reconcileLog.Error(err, "Unable to update Kwite status")
+			log.Error(err, "Unable to update Kwite status")
 			return ctrl.Result{}, err
 		}
 	}
 
-	// reconcile against the various objects
-	if err := r.reconcileDeployment(ctx, req); err != nil {
-		r.reconcileLog.Error(err, "Failed to update Deployment for ", req.NamespacedName.String())
-	}
-	if err := r.reconcileService(ctx, req); err != nil {
-		r.reconcileLog.Error(err, "Failed to update Service for ", req.NamespacedName.String())
-	}
-	if err := r.reconcileHPA(ctx, req); err != nil {
-		r.reconcileLog.Error(err, "Failed to update HPA for ", req.NamespacedName.String())
+	// retry failed children with backoff, and check back on kwites that
+	// are still coming up
+	if reconcileErr != nil {
+		return res, reconcileErr
 	}
-	if err := r.reconcileConfigMap(ctx, req); err != nil {
-		r.reconcileLog.Error(err, "Failed to update ConfigMap for ", req.NamespacedName.String())
+	if !kwite.Status.Ready && r.NotReadyRequeueInterval > 0 {
+		res.RequeueAfter = r.NotReadyRequeueInterval
 	}
-
 	return res, nil
 }
 
+// Update the kwite status from the status of its child resources.
+func (r *reconcileContext) updateChildStatus(ctx context.Context, req ctrl.Request) {
+	r.updateDeploymentStatus(ctx, req)
+	r.updateHPAStatus(ctx, req)
+	r.updateServiceStatus(ctx, req)
+}
+
 func isOwnerKwite(rawObj runtime.Object) []string {
 	cm := rawObj.(*corev1.ConfigMap)
 	owner := metav1.GetControllerOf(cm)
 
 	if owner == nil {
 		return nil
-	} else if owner.APIVersion == webv1beta1.GroupVersion.String() && owner.Kind == webv1beta1.ControllerName {
+	}
+
+	// match on group so owners recorded under any served version count
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil {
+		return nil
+	} else if gv.Group == webv1beta2.GroupVersion.Group && owner.Kind == webv1beta2.ControllerName {
 		return []string{owner.Kind}
 	} else {
 		return nil
@@ -116,14 +216,54 @@ func (r *KwiteReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	if err := mgr.GetFieldIndexer().IndexField(&corev1.ConfigMap{}, cmOwnerKey,
 		isOwnerKwite); err != nil {
-		r.reconcileLog.Error(err, "Aborting setup.")
+		r.Log.Error(err, "Aborting setup.")
 		return nil
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&webv1beta1.Kwite{}).
-		Owns(&corev1.ConfigMap{}).
-		Owns(&corev1.Service{}).
-		Owns(&appsv1.Deployment{}).
+	if err := mgr.GetFieldIndexer().IndexField(&webv1beta2.Kwite{}, kwiteSourceKey,
+		getKwiteSources); err != nil {
+		return err
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(&webv1beta2.Kwite{}, kwiteClassKey,
+		getKwiteClassName); err != nil {
+		return err
+	}
+
+	// status only changes of the children go to a controller of their own,
+	// so they refresh the kwite status without touching the children
+	statusReconciler := &kwiteStatusReconciler{KwiteReconciler: r}
+	c, err := controller.New("kwite-status", mgr, controller.Options{
+		Reconciler:              statusReconciler,
+		MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+	})
+	if err != nil {
+		return err
+	}
+	for _, t := range childTypes {
+		if err := c.Watch(&source.Kind{Type: t},
+			&handler.EnqueueRequestForOwner{OwnerType: &webv1beta2.Kwite{}, IsController: true},
+			onlyStatusUpdates); err != nil {
+			return err
+		}
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&webv1beta2.Kwite{}).
+		WithEventFilter(ignoreStatusUpdates).
+		WithEventFilter(ignoreRewriteUpdates).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles})
+	for _, t := range childTypes {
+		b = b.Owns(t)
+	}
+	return b.
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: r.kwitesForSource(kindConfigMap)}).
+		Watches(&source.Kind{Type: &corev1.Secret{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: r.kwitesForSource(kindSecret)}).
+		Watches(&source.Kind{Type: &webv1beta2.KwiteTemplateLibrary{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: r.kwitesForSource(webv1beta2.LibraryKind)}).
+		Watches(&source.Kind{Type: &webv1beta2.KwiteClass{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.kwitesForClass)}).
 		Complete(r)
 }
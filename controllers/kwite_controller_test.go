@@ -0,0 +1,107 @@
+/*
+kwite_controller_test.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var _ = Describe("KwiteReconciler", func() {
+	const (
+		count = 20
+		image = "registry.hub.docker.com/tdhite/kwite:latest"
+	)
+
+	// Run with the race detector (go test -race) to catch state shared
+	// between concurrent reconciles.
+	It("reconciles many kwites in parallel", func() {
+		ctx := context.Background()
+		r := &KwiteReconciler{
+			Client:   k8sClient,
+			Log:      logf.Log.WithName("controllers").WithName(webv1beta2.ControllerName),
+			Scheme:   scheme.Scheme,
+			Recorder: record.NewFakeRecorder(100 * count),
+
+			MaxConcurrentReconciles: count,
+		}
+
+		reqs := make([]ctrl.Request, 0, count)
+		for i := 0; i < count; i++ {
+			kwite := &webv1beta2.Kwite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("parallel-%d", i),
+					Namespace: "default",
+				},
+				Spec: webv1beta2.KwiteSpec{
+					Image: image,
+					Exposure: webv1beta2.KwiteExposure{
+						Url:  fmt.Sprintf("/kwite-%d", i),
+						Port: 8080,
+					},
+					Template: webv1beta2.KwiteTemplate{
+						Inline: fmt.Sprintf("kwite %d", i),
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, kwite)).To(Succeed())
+			reqs = append(reqs, ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: kwite.Namespace, Name: kwite.Name},
+			})
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, count)
+		for _, req := range reqs {
+			wg.Add(1)
+			go func(req ctrl.Request) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := r.Reconcile(req)
+				errs <- err
+			}(req)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		// every kwite got its own children, not those of another
+		for i, req := range reqs {
+			kwite := &webv1beta2.Kwite{}
+			Expect(k8sClient.Get(ctx, req.NamespacedName, kwite)).To(Succeed())
+			Expect(kwite.Status.ObservedGeneration).To(Equal(kwite.Generation))
+
+			dep := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, req.NamespacedName, dep)).To(Succeed())
+			Expect(metav1.IsControlledBy(dep, kwite)).To(BeTrue())
+			Expect(dep.Spec.Template.Labels).To(HaveKeyWithValue(kwiteName, req.Name))
+
+			cm := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, req.NamespacedName, cm)).To(Succeed())
+			Expect(cm.Data).To(HaveKeyWithValue("url", fmt.Sprintf("/kwite-%d", i)))
+			Expect(cm.Data).To(HaveKeyWithValue("template", fmt.Sprintf("kwite %d", i)))
+		}
+	})
+})
@@ -0,0 +1,59 @@
+/*
+placement.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Return the affinity of the kwite instance Pods. Without any placement
+// set, a kwite running more than one replica prefers its Pods on different
+// nodes and, less so, different zones.
+func (r *reconcileContext) getAffinity(req ctrl.Request) *corev1.Affinity {
+	spec := &r.kwite.Spec
+	if spec.Affinity != nil {
+		return spec.Affinity
+	}
+	if len(spec.TopologySpreadConstraints) > 0 || spec.Scaling.MinReplicas <= 1 {
+		return nil
+	}
+
+	selector := &metav1.LabelSelector{MatchLabels: getLabelSelector(req)}
+	return &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: selector,
+						TopologyKey:   corev1.LabelHostname,
+					},
+				},
+				{
+					Weight: 50,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: selector,
+						TopologyKey:   corev1.LabelZoneFailureDomain,
+					},
+				},
+			},
+		},
+	}
+}
+
+// Set the placement of the kwite instance Pods on the Pod spec.
+func (r *reconcileContext) setPlacement(req ctrl.Request, spec *corev1.PodSpec) {
+	spec.NodeSelector = r.kwite.Spec.NodeSelector
+	spec.Tolerations = r.kwite.Spec.Tolerations
+	spec.Affinity = r.getAffinity(req)
+	spec.TopologySpreadConstraints = r.kwite.Spec.TopologySpreadConstraints
+	spec.PriorityClassName = r.kwite.Spec.PriorityClassName
+}
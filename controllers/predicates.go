@@ -0,0 +1,128 @@
+/*
+predicates.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+)
+
+// Annotations the autoscaling/v1 API holds HPA status in. Others under the
+// same prefix, such as the metrics annotation, hold spec.
+var hpaStatusAnnotations = []string{
+	"autoscaling.alpha.kubernetes.io/conditions",
+	"autoscaling.alpha.kubernetes.io/current-metrics",
+}
+
+// Passes every event except updates that change only the status.
+var ignoreStatusUpdates = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return !isStatusOnlyUpdate(e)
+	},
+}
+
+// Passes only updates that change just the status.
+var onlyStatusUpdates = predicate.Funcs{
+	CreateFunc:  func(event.CreateEvent) bool { return false },
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+	UpdateFunc:  isStatusOnlyUpdate,
+}
+
+// Drops updates to kwite ConfigMaps that change only the rewrite map, which
+// the registry copies into every kwite ConfigMap and the kwites do not use.
+var ignoreRewriteUpdates = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return !isRewriteOnlyUpdate(e)
+	},
+}
+
+// Return whether the update changed the rewrite map of a kwite ConfigMap and
+// nothing else.
+func isRewriteOnlyUpdate(e event.UpdateEvent) bool {
+	oldCM, ok := e.ObjectOld.(*corev1.ConfigMap)
+	if !ok {
+		return false
+	}
+	newCM, ok := e.ObjectNew.(*corev1.ConfigMap)
+	if !ok || len(isOwnerKwite(newCM)) == 0 {
+		return false
+	}
+	if oldCM.Data[cmRewriteKey] == newCM.Data[cmRewriteKey] {
+		return false
+	}
+
+	oldContent, err := withoutStatus(oldCM)
+	if err != nil {
+		return false
+	}
+	newContent, err := withoutStatus(newCM)
+	if err != nil {
+		return false
+	}
+	for _, content := range []map[string]interface{}{oldContent, newContent} {
+		if data, ok := content["data"].(map[string]interface{}); ok {
+			delete(data, cmRewriteKey)
+			if len(data) == 0 {
+				delete(content, "data")
+			}
+		}
+	}
+	return apiequality.Semantic.DeepEqual(oldContent, newContent)
+}
+
+// Return whether the update changed the status of the object and nothing
+// else. Anything that cannot be compared counts as more than a status change.
+func isStatusOnlyUpdate(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil || e.MetaOld == nil || e.MetaNew == nil {
+		return false
+	}
+	if e.MetaOld.GetResourceVersion() == e.MetaNew.GetResourceVersion() {
+		// a resync, not a change
+		return false
+	}
+
+	oldContent, err := withoutStatus(e.ObjectOld)
+	if err != nil {
+		return false
+	}
+	newContent, err := withoutStatus(e.ObjectNew)
+	if err != nil {
+		return false
+	}
+	return apiequality.Semantic.DeepEqual(oldContent, newContent)
+}
+
+// Return the content of the object without its status and the metadata the
+// API server maintains alongside it.
+func withoutStatus(obj runtime.Object) (map[string]interface{}, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(content, "status")
+	if md, ok := content["metadata"].(map[string]interface{}); ok {
+		delete(md, "resourceVersion")
+		delete(md, "managedFields")
+		if annotations, ok := md["annotations"].(map[string]interface{}); ok {
+			for _, k := range hpaStatusAnnotations {
+				delete(annotations, k)
+			}
+			if len(annotations) == 0 {
+				delete(md, "annotations")
+			}
+		}
+	}
+	return content, nil
+}
@@ -0,0 +1,179 @@
+/*
+predicates_test.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
+	appsv1 "k8s.io/api/apps/v1"
+	asv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// Return an update event from the old object to the new one.
+func updateEvent(oldObj, newObj runtime.Object) event.UpdateEvent {
+	return event.UpdateEvent{
+		MetaOld:   oldObj.(metav1.Object),
+		ObjectOld: oldObj,
+		MetaNew:   newObj.(metav1.Object),
+		ObjectNew: newObj,
+	}
+}
+
+func TestIsStatusOnlyUpdate(t *testing.T) {
+	replicas := int32(2)
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "kwite-1", Namespace: "web", ResourceVersion: "1"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	hpa := &asv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "kwite-1",
+			Namespace:       "web",
+			ResourceVersion: "1",
+			Annotations: map[string]string{
+				"autoscaling.alpha.kubernetes.io/conditions":      `[{"type":"AbleToScale","status":"True"}]`,
+				"autoscaling.alpha.kubernetes.io/current-metrics": `[]`,
+				"autoscaling.alpha.kubernetes.io/metrics":         `[{"type":"Resource"}]`,
+			},
+		},
+		Spec: asv1.HorizontalPodAutoscalerSpec{MaxReplicas: 3},
+	}
+
+	tests := []struct {
+		name   string
+		old    runtime.Object
+		update func(obj runtime.Object)
+		resync bool
+		want   bool
+	}{
+		{
+			name: "deployment status",
+			old:  dep,
+			update: func(obj runtime.Object) {
+				obj.(*appsv1.Deployment).Status.ReadyReplicas = 2
+			},
+			want: true,
+		},
+		{
+			name: "deployment spec",
+			old:  dep,
+			update: func(obj runtime.Object) {
+				replicas := int32(3)
+				obj.(*appsv1.Deployment).Spec.Replicas = &replicas
+			},
+		},
+		{
+			name: "deployment labels",
+			old:  dep,
+			update: func(obj runtime.Object) {
+				obj.(*appsv1.Deployment).Labels = map[string]string{"team": "web"}
+			},
+		},
+		{
+			name: "hpa status annotations",
+			old:  hpa,
+			update: func(obj runtime.Object) {
+				a := obj.(*asv1.HorizontalPodAutoscaler).Annotations
+				a["autoscaling.alpha.kubernetes.io/conditions"] = `[{"type":"AbleToScale","status":"False"}]`
+				a["autoscaling.alpha.kubernetes.io/current-metrics"] = `[{"type":"Resource"}]`
+			},
+			want: true,
+		},
+		{
+			name: "hpa metrics annotation",
+			old:  hpa,
+			update: func(obj runtime.Object) {
+				a := obj.(*asv1.HorizontalPodAutoscaler).Annotations
+				a["autoscaling.alpha.kubernetes.io/metrics"] = `[{"type":"Pods"}]`
+			},
+		},
+		{
+			name:   "resync",
+			old:    dep,
+			update: func(obj runtime.Object) {},
+			resync: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newObj := tt.old.DeepCopyObject()
+			tt.update(newObj)
+			if !tt.resync {
+				newObj.(metav1.Object).SetResourceVersion("2")
+			}
+			if got := isStatusOnlyUpdate(updateEvent(tt.old, newObj)); got != tt.want {
+				t.Errorf("isStatusOnlyUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRewriteOnlyUpdate(t *testing.T) {
+	controller := true
+	owned := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "kwite-1",
+			Namespace:       "web",
+			ResourceVersion: "1",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: webv1beta2.GroupVersion.String(),
+				Kind:       webv1beta2.ControllerName,
+				Name:       "kwite-1",
+				Controller: &controller,
+			}},
+		},
+		Data: map[string]string{"url": "/"},
+	}
+	source := owned.DeepCopy()
+	source.OwnerReferences = nil
+
+	tests := []struct {
+		name   string
+		old    *corev1.ConfigMap
+		update func(cm *corev1.ConfigMap)
+		want   bool
+	}{
+		{
+			name:   "rewrite of a kwite configmap",
+			old:    owned,
+			update: func(cm *corev1.ConfigMap) { cm.Data[cmRewriteKey] = `{"a.b":"c"}` },
+			want:   true,
+		},
+		{
+			name: "rewrite and template of a kwite configmap",
+			old:  owned,
+			update: func(cm *corev1.ConfigMap) {
+				cm.Data[cmRewriteKey] = `{"a.b":"c"}`
+				cm.Data["template"] = "hello"
+			},
+		},
+		{
+			name:   "rewrite of another configmap",
+			old:    source,
+			update: func(cm *corev1.ConfigMap) { cm.Data[cmRewriteKey] = `{"a.b":"c"}` },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newCM := tt.old.DeepCopy()
+			tt.update(newCM)
+			newCM.ResourceVersion = "2"
+			if got := isRewriteOnlyUpdate(updateEvent(tt.old, newCM)); got != tt.want {
+				t.Errorf("isRewriteOnlyUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
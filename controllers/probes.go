@@ -0,0 +1,69 @@
+/*
+probes.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	"path"
+
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Return a probe requesting the path, with the settings overriding the given
+// defaults. Every field the API server would default is set, so the operator
+// owns all of them.
+func getProbe(settings *webv1beta2.KwiteProbeSettings, probePath string, period, failure int32) *corev1.Probe {
+	if settings.Path != "" {
+		probePath = settings.Path
+	}
+	if settings.PeriodSeconds > 0 {
+		period = settings.PeriodSeconds
+	}
+	if settings.FailureThreshold > 0 {
+		failure = settings.FailureThreshold
+	}
+	timeout := int32(1)
+	if settings.TimeoutSeconds > 0 {
+		timeout = settings.TimeoutSeconds
+	}
+	success := int32(1)
+	if settings.SuccessThreshold > 0 {
+		success = settings.SuccessThreshold
+	}
+
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: probePath,
+				Port: intstr.IntOrString{
+					IntVal: kwitePort,
+				},
+				Scheme: corev1.URISchemeHTTP,
+			},
+		},
+		InitialDelaySeconds: settings.InitialDelaySeconds,
+		TimeoutSeconds:      timeout,
+		PeriodSeconds:       period,
+		SuccessThreshold:    success,
+		FailureThreshold:    failure,
+	}
+}
+
+// Return the startup, liveness and readiness probes of the kwite container.
+// By default they target the route the kwite serves.
+func (r *reconcileContext) getProbes() (startup, liveness, readiness *corev1.Probe) {
+	probes := &r.kwite.Spec.Probes
+	probeUrl := r.kwite.GetRoutes()[0].Path
+
+	startup = getProbe(&probes.Startup, path.Join(probeUrl, kwiteAlive), 1, 5)
+	liveness = getProbe(&probes.Alive.KwiteProbeSettings, path.Join(probeUrl, kwiteAlive), 3, 3)
+	readiness = getProbe(&probes.Ready.KwiteProbeSettings, path.Join(probeUrl, kwiteReady), 3, 3)
+	return
+}
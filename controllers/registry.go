@@ -0,0 +1,273 @@
+/*
+registry.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// The field manager the registry writes the rewrite maps as
+	registryFieldManager = "kwite-operator-registry"
+
+	// The registry ConfigMap unless configured otherwise
+	defaultRegistryNamespace = "kwiteop-system"
+	defaultRegistryName      = "kwite-rewrite-registry"
+)
+
+// RegistryReconciler keeps the kwite:// rewrite map of every kwite in the
+// cluster, built from the kwite informer, in one ConfigMap. It copies the map
+// into the rewrite key of every kwite ConfigMap, as ConfigMap volumes cannot
+// reach across namespaces.
+type RegistryReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+
+	// The namespace and name of the registry ConfigMap, kwiteop-system and
+	// kwite-rewrite-registry when empty
+	Namespace string
+	Name      string
+
+	// The ConfigMaps of kwites whose annotations match are left alone, as
+	// are those of kwites setting spec.suspend. Nil or empty matches none.
+	SuspendSelector labels.Selector
+
+	// The entries last persisted, keyed by kwite name.namespace. Only the
+	// single registry worker touches them.
+	entries map[string]string
+}
+
+// +kubebuilder:rbac:groups=web.kwite.site,resources=kwites,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch
+
+func (r *RegistryReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("registry", req.NamespacedName)
+
+	registry := &corev1.ConfigMap{}
+	if err := r.Get(ctx, req.NamespacedName, registry); err != nil && !apierrs.IsNotFound(err) {
+		log.Error(err, "Unable to fetch the registry ConfigMap")
+		return ctrl.Result{}, err
+	}
+	if r.entries == nil {
+		// pick up where the last run left off, so only real changes are
+		// announced
+		r.entries = make(map[string]string)
+		if s := registry.Data[cmRewriteKey]; s != "" {
+			if err := json.Unmarshal([]byte(s), &r.entries); err != nil {
+				log.Error(err, "Ignoring the unreadable registry ConfigMap content")
+			}
+		}
+	}
+
+	var kwites webv1beta2.KwiteList
+	if err := r.List(ctx, &kwites); err != nil {
+		log.Error(err, "Unable to list kwites")
+		return ctrl.Result{}, err
+	}
+	entries, changed := r.getEntries(kwites.Items)
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		log.Error(err, "Failed to convert rewrite map to JSON.")
+		return ctrl.Result{}, err
+	}
+	rewrite := string(b)
+	if len(rewrite) > webv1beta2.MaxConfigMapSize/2 {
+		// every kwite ConfigMap holds a copy alongside its own content
+		log.Info("The rewrite map takes more than half the ConfigMap limit",
+			"bytes", len(rewrite), "limit", webv1beta2.MaxConfigMapSize)
+	}
+
+	if registry.Data[cmRewriteKey] != rewrite {
+		log.Info("Updating the rewrite registry", "entries", len(entries))
+		if err := r.applyRegistry(ctx, req, rewrite); err != nil {
+			log.Error(err, "Failed to update the registry ConfigMap")
+			for _, kwite := range changed {
+				r.Recorder.Eventf(kwite, corev1.EventTypeWarning, eventReasonRewriteUpdateFailed,
+					"Failed to set the rewrite entry for %s: %v", registryKey(kwite), err)
+			}
+			return ctrl.Result{}, err
+		}
+	}
+	r.entries = entries
+	for _, kwite := range changed {
+		r.Recorder.Eventf(kwite, corev1.EventTypeNormal, eventReasonRewriteUpdated,
+			"Set the rewrite entry for %s to %s", registryKey(kwite), kwite.Status.Address)
+	}
+
+	if err := r.copyToKwites(ctx, rewrite, getSuspendedKwites(kwites.Items, r.SuspendSelector)); err != nil {
+		log.Error(err, "Failed to copy the rewrite map to kwite ConfigMaps")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// Return the rewrite key of the kwite.
+func registryKey(kwite *webv1beta2.Kwite) string {
+	return kwite.Name + "." + kwite.Namespace
+}
+
+// Return the rewrite entries of the kwites with an address that are not
+// being deleted, along with the kwites whose entries are new or changed.
+func (r *RegistryReconciler) getEntries(kwites []webv1beta2.Kwite) (map[string]string, []*webv1beta2.Kwite) {
+	entries := make(map[string]string)
+	var changed []*webv1beta2.Kwite
+	for i := range kwites {
+		kwite := &kwites[i]
+		if kwite.Status.Address == "" || !kwite.DeletionTimestamp.IsZero() {
+			continue
+		}
+		key := registryKey(kwite)
+		entries[key] = kwite.Status.Address
+		if r.entries[key] != kwite.Status.Address {
+			changed = append(changed, kwite)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		return registryKey(changed[i]) < registryKey(changed[j])
+	})
+	return entries, changed
+}
+
+// Write the rewrite map to the registry ConfigMap, creating it if need be.
+func (r *RegistryReconciler) applyRegistry(ctx context.Context, req ctrl.Request, rewrite string) error {
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      req.Name,
+			Namespace: req.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "kwite-operator",
+			},
+		},
+		Data: map[string]string{
+			cmRewriteKey: rewrite,
+		},
+	}
+	return r.Patch(ctx, cm, client.Apply, client.FieldOwner(registryFieldManager), client.ForceOwnership)
+}
+
+// Set the rewrite key of every kwite ConfigMap that differs from the map.
+// ConfigMaps that are gone are skipped rather than created again, as are
+// those of suspended kwites, which get the map once they resume.
+func (r *RegistryReconciler) copyToKwites(ctx context.Context, rewrite string, suspended map[types.NamespacedName]bool) error {
+	var cmList corev1.ConfigMapList
+	if err := r.List(ctx, &cmList, client.MatchingFields{cmOwnerKey: webv1beta2.ControllerName}); err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range cmList.Items {
+		cm := &cmList.Items[i]
+		if cm.Data[cmRewriteKey] == rewrite || !cm.DeletionTimestamp.IsZero() || isSuspendedConfigMap(cm, suspended) {
+			continue
+		}
+		if err := checkConfigMapSize(withoutRewrite(cm), rewrite); err != nil {
+			// retrying cannot help until the map or the kwite shrinks
+			r.Log.Error(err, "Not copying the rewrite map", "configmap", cm.Namespace+"/"+cm.Name)
+			if owner := metav1.GetControllerOf(cm); owner != nil {
+				kwite := &webv1beta2.Kwite{ObjectMeta: metav1.ObjectMeta{
+					Name: owner.Name, Namespace: cm.Namespace, UID: owner.UID,
+				}}
+				r.Recorder.Eventf(kwite, corev1.EventTypeWarning, eventReasonRewriteUpdateFailed,
+					"Failed to copy the rewrite map: %v", err)
+			}
+			continue
+		}
+		if err := setRewrite(ctx, r, cm, rewrite); err != nil && !apierrs.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// Return a copy of the ConfigMap without its rewrite key.
+func withoutRewrite(cm *corev1.ConfigMap) *corev1.ConfigMap {
+	cm = cm.DeepCopy()
+	delete(cm.Data, cmRewriteKey)
+	return cm
+}
+
+// Set the rewrite key of the kwite ConfigMap with a merge patch, which
+// fails rather than creating the ConfigMap if it is gone.
+func setRewrite(ctx context.Context, c client.Client, cm *corev1.ConfigMap, rewrite string) error {
+	patch := client.MergeFrom(cm.DeepCopy())
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[cmRewriteKey] = rewrite
+	return c.Patch(ctx, cm, patch, client.FieldOwner(registryFieldManager))
+}
+
+// Map any event to the single registry request.
+func (r *RegistryReconciler) registryRequest(handler.MapObject) []reconcile.Request {
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Namespace: r.Namespace, Name: r.Name},
+	}}
+}
+
+// Map the registry ConfigMap and the kwite ConfigMaps to the registry request.
+func (r *RegistryReconciler) registryRequestForConfigMap(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta.GetNamespace() == r.Namespace && obj.Meta.GetName() == r.Name {
+		return r.registryRequest(obj)
+	}
+	if len(isOwnerKwite(obj.Object)) > 0 {
+		return r.registryRequest(obj)
+	}
+	return nil
+}
+
+// SetupWithManager relies on the ConfigMap owner index the KwiteReconciler
+// sets up, so it must be set up after it.
+func (r *RegistryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Namespace == "" {
+		r.Namespace = defaultRegistryNamespace
+	}
+	if r.Name == "" {
+		r.Name = defaultRegistryName
+	}
+
+	// every event maps to the one registry request, so a single worker
+	// rebuilds the map however many kwites change at once
+	c, err := controller.New("kwite-registry", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	if err := c.Watch(&source.Kind{Type: &webv1beta2.Kwite{}},
+		&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.registryRequest)}); err != nil {
+		return err
+	}
+	return c.Watch(&source.Kind{Type: &corev1.ConfigMap{}},
+		&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.registryRequestForConfigMap)})
+}
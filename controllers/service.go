@@ -23,8 +23,13 @@ import (
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 )
 
-// Reconcile the Horizontal Pod Autoscaler cluster state.
-func (r *KwiteReconciler) getService(req ctrl.Request) (*corev1.Service, error) {
+const (
+	// The DNS domain of the cluster unless configured otherwise
+	defaultClusterDomain = "cluster.local"
+)
+
+// Create, initialize and return a new Service.
+func (r *reconcileContext) getService(req ctrl.Request) (*corev1.Service, error) {
 	s := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      req.Name,
@@ -37,7 +42,7 @@ func (r *KwiteReconciler) getService(req ctrl.Request) (*corev1.Service, error)
 				{
 					Name:     kwiteName + "-ext",
 					Protocol: "TCP",
-					Port:     int32(r.kwite.Spec.Port),
+					Port:     r.kwite.Spec.Exposure.Port,
 					TargetPort: intstr.IntOrString{
 						IntVal: kwitePort,
 					},
@@ -54,46 +59,22 @@ func (r *KwiteReconciler) getService(req ctrl.Request) (*corev1.Service, error)
 	return s, nil
 }
 
-// Determine and return the fqdn for the kwite
-func (r *KwiteReconciler) getKwiteFqdn(key string, svc *corev1.Service) string {
-	hn := key
-	ips, err := net.LookupIP(hn)
-	if err != nil {
-		r.reconcileLog.Error(err, "Failed address lookup kwite hostname "+hn)
-		return hn
-	}
-
-	for _, ip := range ips {
-		v4 := ip.To4()
-		if v4 == nil {
-			continue
-		}
+// Determine and return the address of the kwite Service, either its
+// cluster DNS name or, in ClusterIP mode, its cluster IP, with the port.
+// A headless Service has no cluster IP, so its DNS name is used instead.
+func (r *KwiteReconciler) getKwiteAddress(svc *corev1.Service) string {
+	port := strconv.Itoa(int(svc.Spec.Ports[0].Port))
 
-		// marshal the ip address as string-able
-		if ipaddr, err := ip.MarshalText(); err != nil {
-			r.reconcileLog.Error(err, "Failed to marshall kwite address to string-able type.")
-		} else {
-			s := string(ipaddr)
-			if hosts, err := net.LookupAddr(s); err != nil {
-				r.reconcileLog.Error(err, "Failed to obtain fqdn for "+s)
-			} else {
-				// the first address is the fqdn; don't want the trailing dot
-				hn = ""
-				for _, h := range hosts {
-					r.reconcileLog.Info("Found fqdn: " + h)
-					if len(h) > len(hn) {
-						hn = h
-					}
-				}
-				hn = strings.TrimSuffix(hn, ".")
-				hn = net.JoinHostPort(hn, strconv.Itoa(int(svc.Spec.Ports[0].Port)))
-				r.reconcileLog.Info("Finalized on fqdn: " + hn)
-			}
-		}
-		break
+	if r.UseClusterIP && svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		return net.JoinHostPort(svc.Spec.ClusterIP, port)
 	}
 
-	return hn
+	domain := r.ClusterDomain
+	if domain == "" {
+		domain = defaultClusterDomain
+	}
+	host := fmt.Sprintf("%s.%s.svc.%s", svc.Name, svc.Namespace, strings.TrimSuffix(domain, "."))
+	return net.JoinHostPort(host, port)
 }
 
 // Build and return the rewrite (key) for the recncile request.
@@ -103,9 +84,8 @@ func (r *KwiteReconciler) getServiceHostName(req ctrl.Request) string {
 	return fmt.Sprintf("%s.%s", req.Name, req.Namespace)
 }
 
-func (r *KwiteReconciler) updateServiceStatus(ctx context.Context, req ctrl.Request) bool {
+func (r *reconcileContext) updateServiceStatus(ctx context.Context, req ctrl.Request) {
 	svc := &corev1.Service{}
-	doUpdate := false
 
 	if err := r.Get(ctx, req.NamespacedName, svc); err != nil {
 		if apierrs.IsNotFound(err) {
@@ -113,67 +93,39 @@ func (r *KwiteReconciler) updateServiceStatus(ctx context.Context, req ctrl.Requ
 		} else {
 			r.reconcileLog.Error(err, "Failed Service retrieve for status update in namespace: "+req.NamespacedName.String())
 		}
+	} else if !metav1.IsControlledBy(svc, r.kwite) || len(svc.Spec.Ports) == 0 {
+		// a Service the kwite does not control, or one without ports, is no
+		// address of the kwite
+		r.reconcileLog.Info("Service is not a kwite Service, leaving the address alone for " + req.NamespacedName.String())
 	} else {
-		newAddr := r.getKwiteFqdn(r.getServiceHostName(req), svc)
+		newAddr := r.getKwiteAddress(svc)
 		if newAddr != r.kwite.Status.Address {
 			r.reconcileLog.Info("Service address changed, updates to Kwite rewrite rules necessary for " + req.NamespacedName.String())
+			if r.kwite.Status.Address == "" {
+				r.Recorder.Eventf(r.kwite, corev1.EventTypeNormal, eventReasonAddressChanged,
+					"Kwite address set to %s", newAddr)
+			} else {
+				r.Recorder.Eventf(r.kwite, corev1.EventTypeNormal, eventReasonAddressChanged,
+					"Kwite address changed from %s to %s", r.kwite.Status.Address, newAddr)
+			}
 			r.kwite.Status.Address = newAddr
-			doUpdate = true
 		} else {
 			r.reconcileLog.Info("No Srvice address change, updates to Kwite rewrite rules unnecessary for " + req.NamespacedName.String())
 		}
 	}
-
-	return doUpdate
 }
 
 // Reconcile the Service cluster state.
-func (r *KwiteReconciler) reconcileService(ctx context.Context, req ctrl.Request) error {
-	svc := &corev1.Service{}
-
-	if err := r.Get(ctx, req.NamespacedName, svc); err != nil {
-		if apierrs.IsNotFound(err) {
-			// Need to create the service since it's not there
-			svc, err = r.getService(req)
-			if err != nil {
-				r.reconcileLog.Error(err, "failed to create Service resource")
-				return err
-			}
-			if err := r.Create(ctx, svc); err != nil {
-				r.reconcileLog.Error(err, "failed to create Service on the cluster: ")
-				return err
-			}
-		} else {
-			r.reconcileLog.Error(err, "unable to retrieve Service in namespace "+req.Namespace)
-			return err
-		}
+func (r *reconcileContext) reconcileService(ctx context.Context, req ctrl.Request) error {
+	svc, err := r.getService(req)
+	if err != nil {
+		r.reconcileLog.Error(err, "failed to create Service resource")
+		return err
 	}
 
-	// Check current state against the loaded service and update as needed.
-	// However, if deleting, just leave it alone.
-	doUpdate := false
-	if svc.ObjectMeta.DeletionTimestamp.IsZero() {
-		// check current state against the loaded deployment and update as needed
-		iVal := int(svc.Spec.Ports[0].Port)
-
-		if r.kwite.Spec.Port != iVal {
-			svc.Spec.Ports[0].Port = int32(r.kwite.Spec.Port)
-			doUpdate = true
-		}
-		if svc.Spec.Ports[0].TargetPort.IntValue() != int(kwitePort) {
-			svc.Spec.Ports[0].TargetPort = intstr.IntOrString{
-				StrVal: kwiteName,
-			}
-			doUpdate = true
-		}
-		if doUpdate {
-			r.reconcileLog.Info("Updating Service " + svc.GetName())
-			err := r.Update(ctx, svc)
-			if err != nil {
-				r.reconcileLog.Error(err, "Failed to update Service.")
-				return err
-			}
-		}
+	if err := r.applyChild(ctx, svc); err != nil {
+		r.reconcileLog.Error(err, "Failed to apply Service.")
+		return err
 	}
 
 	return nil
@@ -0,0 +1,83 @@
+/*
+service_test.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetKwiteAddress(t *testing.T) {
+	tests := []struct {
+		name          string
+		clusterDomain string
+		useClusterIP  bool
+		clusterIP     string
+		want          string
+	}{
+		{
+			name:      "default cluster domain",
+			clusterIP: "10.0.0.10",
+			want:      "kwite-1.web.svc.cluster.local:8080",
+		},
+		{
+			name:          "custom cluster domain",
+			clusterDomain: "example.org",
+			clusterIP:     "10.0.0.10",
+			want:          "kwite-1.web.svc.example.org:8080",
+		},
+		{
+			name:          "trailing dot cluster domain",
+			clusterDomain: "example.org.",
+			clusterIP:     "10.0.0.10",
+			want:          "kwite-1.web.svc.example.org:8080",
+		},
+		{
+			name:         "cluster IP",
+			useClusterIP: true,
+			clusterIP:    "10.0.0.10",
+			want:         "10.0.0.10:8080",
+		},
+		{
+			name:         "IPv6 cluster IP",
+			useClusterIP: true,
+			clusterIP:    "fd00::10",
+			want:         "[fd00::10]:8080",
+		},
+		{
+			name:         "headless falls back to the DNS name",
+			useClusterIP: true,
+			clusterIP:    corev1.ClusterIPNone,
+			want:         "kwite-1.web.svc.cluster.local:8080",
+		},
+		{
+			name:         "unallocated cluster IP falls back to the DNS name",
+			useClusterIP: true,
+			want:         "kwite-1.web.svc.cluster.local:8080",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &KwiteReconciler{ClusterDomain: tt.clusterDomain, UseClusterIP: tt.useClusterIP}
+			svc := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "kwite-1", Namespace: "web"},
+				Spec: corev1.ServiceSpec{
+					ClusterIP: tt.clusterIP,
+					Ports:     []corev1.ServicePort{{Port: 8080}},
+				},
+			}
+			if got := r.getKwiteAddress(svc); got != tt.want {
+				t.Errorf("getKwiteAddress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+/*
+status.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Condition reasons, kept stable so they may be relied on by tooling.
+const (
+	reasonMinimumReplicasAvailable   = "MinimumReplicasAvailable"
+	reasonMinimumReplicasUnavailable = "MinimumReplicasUnavailable"
+	reasonDeploymentNotFound         = "DeploymentNotFound"
+	reasonRolloutInProgress          = "RolloutInProgress"
+	reasonRolloutComplete            = "RolloutComplete"
+	reasonProgressDeadlineExceeded   = "ProgressDeadlineExceeded"
+	reasonReplicaFailure             = "ReplicaFailure"
+	reasonAsExpected                 = "AsExpected"
+	reasonTemplatesParsed            = "TemplatesParsed"
+	reasonTemplateParseError         = "TemplateParseError"
+	reasonTemplateSourceError        = "TemplateSourceError"
+	reasonTemplateLibraryError       = "TemplateLibraryError"
+	reasonReconcileSucceeded         = "ReconcileSucceeded"
+	reasonReconcileFailed            = "ReconcileFailed"
+	reasonSuspendedBySpec            = "SuspendedBySpec"
+	reasonSuspendedByAnnotation      = "SuspendedByAnnotation"
+	reasonNotSuspended               = "NotSuspended"
+	reasonFieldOwnershipConflict     = "FieldOwnershipConflict"
+	reasonNoConflicts                = "NoConflicts"
+)
+
+// Set a condition on the kwite being reconciled, stamped with its generation.
+func (r *reconcileContext) setCondition(t webv1beta2.KwiteConditionType, status corev1.ConditionStatus, reason, message string) {
+	r.kwite.Status.SetCondition(webv1beta2.KwiteCondition{
+		Type:               t,
+		Status:             status,
+		ObservedGeneration: r.kwite.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// Record whether the kwite templates load and parse.
+func (r *reconcileContext) updateTemplateStatus(ctx context.Context) {
+	resolved, err := r.getResolvedKwite(ctx)
+	if err != nil {
+		r.setTemplateInvalid(reasonTemplateSourceError, err.Error())
+		return
+	}
+
+	libs, err := r.kwite.GetLibraries(ctx, r)
+	if err != nil {
+		r.setTemplateInvalid(reasonTemplateLibraryError, err.Error())
+	} else if errs := resolved.ValidateTemplates(libs); len(errs) > 0 {
+		r.setTemplateInvalid(reasonTemplateParseError, errs.ToAggregate().Error())
+	} else {
+		r.setCondition(webv1beta2.KwiteTemplateValid, corev1.ConditionTrue, reasonTemplatesParsed, "All templates parsed successfully")
+	}
+}
+
+// Record that the kwite templates do not load or parse, with a warning event
+// when they were valid before, so a kwite requeued while invalid does not
+// repeat it.
+func (r *reconcileContext) setTemplateInvalid(reason, message string) {
+	if c := r.kwite.Status.GetCondition(webv1beta2.KwiteTemplateValid); c == nil || c.Status != corev1.ConditionFalse {
+		r.Recorder.Event(r.kwite, corev1.EventTypeWarning, eventReasonTemplateInvalid, message)
+	}
+	r.setCondition(webv1beta2.KwiteTemplateValid, corev1.ConditionFalse, reason, message)
+}
+
+// Record the outcome of reconciling the child resources, naming each child
+// that failed. A failure also marks the kwite degraded, overriding what the
+// Deployment reported.
+func (r *reconcileContext) updateReconciledStatus(err error) {
+	if err != nil {
+		msg := "Failed to reconcile " + err.Error()
+		r.Recorder.Event(r.kwite, corev1.EventTypeWarning, eventReasonReconcileFailed, msg)
+		r.setCondition(webv1beta2.KwiteChildResourcesReconciled, corev1.ConditionFalse, reasonReconcileFailed, msg)
+		r.setCondition(webv1beta2.KwiteDegraded, corev1.ConditionTrue, reasonReconcileFailed, msg)
+	} else {
+		r.setCondition(webv1beta2.KwiteChildResourcesReconciled, corev1.ConditionTrue, reasonReconcileSucceeded, "All child resources reconciled")
+	}
+}
+
+// Keep the kwite degraded while its child resources last failed to
+// reconcile, whatever the Deployment reported since.
+func (r *reconcileContext) keepReconcileFailure() {
+	if c := r.kwite.Status.GetCondition(webv1beta2.KwiteChildResourcesReconciled); c != nil && c.Status == corev1.ConditionFalse {
+		r.setCondition(webv1beta2.KwiteDegraded, corev1.ConditionTrue, reasonReconcileFailed, c.Message)
+	}
+}
+
+// Record the fields of child resources another field manager had taken over
+// and the operator took back, if any.
+func (r *reconcileContext) updateConflictStatus() {
+	if len(r.conflicts) > 0 {
+		r.setCondition(webv1beta2.KwiteFieldConflict, corev1.ConditionTrue, reasonFieldOwnershipConflict, strings.Join(r.conflicts, "; "))
+	} else {
+		r.setCondition(webv1beta2.KwiteFieldConflict, corev1.ConditionFalse, reasonNoConflicts, "No field ownership conflicts")
+	}
+}
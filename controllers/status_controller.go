@@ -0,0 +1,53 @@
+/*
+status_controller.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	"context"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+)
+
+// kwiteStatusReconciler refreshes the status of a Kwite when only the status
+// of one of its child resources changed, leaving the child resources alone.
+type kwiteStatusReconciler struct {
+	*KwiteReconciler
+}
+
+func (r *kwiteStatusReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues(kwiteName, req.NamespacedName)
+
+	var kwite webv1beta2.Kwite
+	if err := r.Get(ctx, req.NamespacedName, &kwite); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !kwite.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	rc := &reconcileContext{KwiteReconciler: r.KwiteReconciler, reconcileLog: log, kwite: &kwite}
+	oldStatus := kwite.Status.DeepCopy()
+	rc.applyKwiteClass(ctx)
+	rc.updateChildStatus(ctx, req)
+	rc.keepReconcileFailure()
+
+	if !apiequality.Semantic.DeepEqual(oldStatus, &kwite.Status) {
+		if err := r.Status().Update(ctx, &kwite); err != nil {
+			log.Error(err, "Unable to update Kwite status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
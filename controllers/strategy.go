@@ -0,0 +1,64 @@
+/*
+strategy.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// Deployment defaults applied by the API server
+	defaultMaxSurge                = "25%"
+	defaultMaxUnavailable          = "25%"
+	defaultProgressDeadlineSeconds = int32(600)
+	defaultRevisionHistoryLimit    = int32(10)
+)
+
+// Return the rollout strategy of the kwite Deployment. Every field the API
+// server would default is set, so the operator owns all of them.
+func (r *reconcileContext) getStrategy() appsv1.DeploymentStrategy {
+	strategy := *r.kwite.Spec.Strategy.DeepCopy()
+	if strategy.Type == "" {
+		strategy.Type = appsv1.RollingUpdateDeploymentStrategyType
+	}
+	if strategy.Type != appsv1.RollingUpdateDeploymentStrategyType {
+		return strategy
+	}
+
+	if strategy.RollingUpdate == nil {
+		strategy.RollingUpdate = &appsv1.RollingUpdateDeployment{}
+	}
+	if strategy.RollingUpdate.MaxSurge == nil {
+		maxSurge := intstr.FromString(defaultMaxSurge)
+		strategy.RollingUpdate.MaxSurge = &maxSurge
+	}
+	if strategy.RollingUpdate.MaxUnavailable == nil {
+		maxUnavailable := intstr.FromString(defaultMaxUnavailable)
+		strategy.RollingUpdate.MaxUnavailable = &maxUnavailable
+	}
+	return strategy
+}
+
+// Set the rollout settings of the kwite on the Deployment spec.
+func (r *reconcileContext) setRollout(spec *appsv1.DeploymentSpec) {
+	progressDeadline := defaultProgressDeadlineSeconds
+	if r.kwite.Spec.ProgressDeadlineSeconds != nil {
+		progressDeadline = *r.kwite.Spec.ProgressDeadlineSeconds
+	}
+	revisionHistory := defaultRevisionHistoryLimit
+	if r.kwite.Spec.RevisionHistoryLimit != nil {
+		revisionHistory = *r.kwite.Spec.RevisionHistoryLimit
+	}
+
+	spec.Strategy = r.getStrategy()
+	spec.MinReadySeconds = r.kwite.Spec.MinReadySeconds
+	spec.ProgressDeadlineSeconds = &progressDeadline
+	spec.RevisionHistoryLimit = &revisionHistory
+}
@@ -16,6 +16,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	webv1beta1 "github.com/tdhite/kwite-operator/api/v1beta1"
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -56,6 +57,9 @@ var _ = BeforeSuite(func(done Done) {
 	err = webv1beta1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = webv1beta2.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	// +kubebuilder:scaffold:scheme
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
@@ -0,0 +1,65 @@
+/*
+suspend.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Record whether reconciling the kwite children is suspended, either by
+// spec.suspend or by annotations matching the operator suspend selector, and
+// return whether it is.
+func (r *reconcileContext) updateSuspendedStatus() bool {
+	if r.kwite.Spec.Suspend {
+		r.setCondition(webv1beta2.KwiteSuspended, corev1.ConditionTrue, reasonSuspendedBySpec,
+			"Child resources are not reconciled while spec.suspend is set")
+		return true
+	}
+
+	if matchesSuspendSelector(r.kwite, r.SuspendSelector) {
+		r.setCondition(webv1beta2.KwiteSuspended, corev1.ConditionTrue, reasonSuspendedByAnnotation,
+			"Child resources are not reconciled while the annotations match "+r.SuspendSelector.String())
+		return true
+	}
+
+	r.setCondition(webv1beta2.KwiteSuspended, corev1.ConditionFalse, reasonNotSuspended, "")
+	return false
+}
+
+// Return whether the kwite annotations match the suspend selector. Nil or
+// empty selectors match no kwite.
+func matchesSuspendSelector(kwite *webv1beta2.Kwite, selector labels.Selector) bool {
+	return selector != nil && !selector.Empty() && selector.Matches(labels.Set(kwite.Annotations))
+}
+
+// Return whether reconciling the kwite children is suspended.
+func isSuspended(kwite *webv1beta2.Kwite, selector labels.Selector) bool {
+	return kwite.Spec.Suspend || matchesSuspendSelector(kwite, selector)
+}
+
+// Return the kwites that are suspended, by namespace and name.
+func getSuspendedKwites(kwites []webv1beta2.Kwite, selector labels.Selector) map[types.NamespacedName]bool {
+	suspended := make(map[types.NamespacedName]bool)
+	for i := range kwites {
+		if isSuspended(&kwites[i], selector) {
+			suspended[types.NamespacedName{Namespace: kwites[i].Namespace, Name: kwites[i].Name}] = true
+		}
+	}
+	return suspended
+}
+
+// Return whether the ConfigMap belongs to one of the suspended kwites.
+func isSuspendedConfigMap(cm *corev1.ConfigMap, suspended map[types.NamespacedName]bool) bool {
+	owner := metav1.GetControllerOf(cm)
+	return owner != nil && suspended[types.NamespacedName{Namespace: cm.Namespace, Name: owner.Name}]
+}
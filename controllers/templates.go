@@ -0,0 +1,107 @@
+/*
+templates.go
+
+Copyright (c) 2019-2020 VMware, Inc.
+
+SPDX-License-Identifier: https://spdx.org/licenses/MIT.html
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// Index of the ConfigMaps, Secrets and template libraries a kwite
+	// reads, as "Kind/name"
+	kwiteSourceKey = ".spec.sources"
+
+	kindConfigMap = "ConfigMap"
+	kindSecret    = "Secret"
+)
+
+// Return the ConfigMaps, Secrets and template libraries the kwite reads, for
+// indexing.
+func getKwiteSources(rawObj runtime.Object) []string {
+	kwite := rawObj.(*webv1beta2.Kwite)
+	var sources []string
+
+	kwite.VisitTemplates(func(fldPath *field.Path, name string, t *webv1beta2.KwiteTemplate) {
+		if t.From == nil {
+			return
+		}
+		if t.From.ConfigMapKeyRef != nil {
+			sources = append(sources, kindConfigMap+"/"+t.From.ConfigMapKeyRef.Name)
+		}
+		if t.From.SecretKeyRef != nil {
+			sources = append(sources, kindSecret+"/"+t.From.SecretKeyRef.Name)
+		}
+	})
+
+	for _, ref := range kwite.Spec.Libraries {
+		sources = append(sources, webv1beta2.LibraryKind+"/"+ref.Name)
+	}
+
+	sources = append(sources, getEnvSources(kwite)...)
+
+	return sources
+}
+
+// Return a function mapping a ConfigMap, Secret or template library of the
+// given kind to reconcile requests for the kwites that read it.
+func (r *KwiteReconciler) kwitesForSource(kind string) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []reconcile.Request {
+		var kwites webv1beta2.KwiteList
+		if err := r.List(context.Background(), &kwites, client.InNamespace(obj.Meta.GetNamespace()),
+			client.MatchingFields{kwiteSourceKey: kind + "/" + obj.Meta.GetName()}); err != nil {
+			r.Log.Error(err, "Unable to list kwites reading "+kind+" "+obj.Meta.GetName())
+			return nil
+		}
+
+		reqs := make([]reconcile.Request, 0, len(kwites.Items))
+		for _, kwite := range kwites.Items {
+			reqs = append(reqs, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: kwite.Namespace, Name: kwite.Name},
+			})
+		}
+		return reqs
+	}
+}
+
+// Return a copy of the kwite with every template sourced from a ConfigMap
+// or Secret loaded inline.
+func (r *reconcileContext) getResolvedKwite(ctx context.Context) (*webv1beta2.Kwite, error) {
+	kwite := r.kwite.DeepCopy()
+	var errs []error
+
+	kwite.VisitTemplates(func(fldPath *field.Path, name string, t *webv1beta2.KwiteTemplate) {
+		if t.From == nil {
+			return
+		}
+		text, err := t.From.Resolve(ctx, r, kwite.Namespace)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", fldPath.Child("from"), err))
+			return
+		}
+		t.Inline = text
+		t.From = nil
+	})
+
+	if len(errs) > 0 {
+		err := utilerrors.NewAggregate(errs)
+		r.reconcileLog.Error(err, "Failed to load template sources")
+		return nil, err
+	}
+	return kwite, nil
+}
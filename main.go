@@ -11,12 +11,15 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	webv1beta1 "github.com/tdhite/kwite-operator/api/v1beta1"
+	webv1beta2 "github.com/tdhite/kwite-operator/api/v1beta2"
 	"github.com/tdhite/kwite-operator/controllers"
 
 	//appsv1 "k8s.io/api/apps/v1"
 	//corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
@@ -36,15 +39,37 @@ func init() {
 	//_ = appsv1.AddToScheme(scheme)
 	//_ = corev1.AddToScheme(scheme)
 	_ = webv1beta1.AddToScheme(scheme)
+	_ = webv1beta2.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var suspendSelector string
+	var clusterDomain string
+	var useClusterIP bool
+	var maxConcurrentReconciles int
+	var notReadyRequeueInterval time.Duration
+	var registryNamespace string
+	var registryName string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&clusterDomain, "cluster-domain", "cluster.local",
+		"The DNS domain of the cluster, used to build kwite Service addresses.")
+	flag.BoolVar(&useClusterIP, "use-cluster-ip", false,
+		"Address kwite Services by cluster IP instead of DNS name.")
+	flag.StringVar(&suspendSelector, "suspend-annotation-selector", "",
+		"Suspend reconciling the child resources of kwites whose annotations match this selector (e.g., kwite.site/incident).")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"The maximum number of kwites reconciled concurrently.")
+	flag.DurationVar(&notReadyRequeueInterval, "not-ready-requeue-interval", 30*time.Second,
+		"How often to reconcile kwites that are not ready, or 0 to wait for changes.")
+	flag.StringVar(&registryNamespace, "registry-namespace", "kwiteop-system",
+		"The namespace of the ConfigMap holding the kwite:// rewrite map of every kwite.")
+	flag.StringVar(&registryName, "registry-name", "kwite-rewrite-registry",
+		"The name of the ConfigMap holding the kwite:// rewrite map of every kwite.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(func(o *zap.Options) {
@@ -62,12 +87,37 @@ func main() {
 		os.Exit(1)
 	}
 
+	suspend, err := labels.Parse(suspendSelector)
+	if err != nil {
+		setupLog.Error(err, "invalid suspend annotation selector")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.KwiteReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName(webv1beta1.ControllerName),
-		Scheme: mgr.GetScheme(),
+		Client:          mgr.GetClient(),
+		Log:             ctrl.Log.WithName("controllers").WithName(webv1beta2.ControllerName),
+		Scheme:          mgr.GetScheme(),
+		Recorder:        mgr.GetEventRecorderFor("kwite-controller"),
+		ClusterDomain:   clusterDomain,
+		UseClusterIP:    useClusterIP,
+		SuspendSelector: suspend,
+
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		NotReadyRequeueInterval: notReadyRequeueInterval,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", webv1beta1.ControllerName)
+		setupLog.Error(err, "unable to create controller", "controller", webv1beta2.ControllerName)
+		os.Exit(1)
+	}
+	if err = (&controllers.RegistryReconciler{
+		Client:    mgr.GetClient(),
+		Log:       ctrl.Log.WithName("controllers").WithName("Registry"),
+		Recorder:  mgr.GetEventRecorderFor("kwite-registry"),
+		Namespace: registryNamespace,
+		Name:      registryName,
+
+		SuspendSelector: suspend,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Registry")
 		os.Exit(1)
 	}
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
@@ -75,6 +125,14 @@ func main() {
 			setupLog.Error(err, "unable to create webhook", "webhook", webv1beta1.ControllerName)
 			os.Exit(1)
 		}
+		if err = (&webv1beta2.Kwite{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", webv1beta2.ControllerName)
+			os.Exit(1)
+		}
+		if err = (&webv1beta2.KwiteTemplateLibrary{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", webv1beta2.LibraryKind)
+			os.Exit(1)
+		}
 	}
 	// +kubebuilder:scaffold:builder
 